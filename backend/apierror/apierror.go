@@ -0,0 +1,73 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"resume-learning-backend/logging"
+)
+
+const ContentType = "application/problem+json"
+
+const (
+	CodeBadRequest          = "bad_request"
+	CodeInvalidBody         = "invalid_body"
+	CodeBodyTooLarge        = "body_too_large"
+	CodeValidation          = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeRateLimited         = "rate_limited"
+	CodeConflict            = "conflict"
+	CodeIdempotencyMismatch = "idempotency_key_mismatch"
+	CodeInternal            = "internal_error"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object. Code, RequestID and Errors
+// are extension members: a stable machine-readable error code, the request
+// ID for correlating with server logs, and per-field validation failures.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Write(w http.ResponseWriter, r *http.Request) {
+	p.Instance = r.URL.Path
+	p.RequestID = logging.RequestID(r.Context())
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func Respond(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	New(status, code, detail).Write(w, r)
+}
+
+func RespondValidation(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	p := New(http.StatusUnprocessableEntity, CodeValidation, "Request validation failed")
+	p.Errors = errs
+	p.Write(w, r)
+}
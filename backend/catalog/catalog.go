@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/metrics"
+	"resume-learning-backend/models"
+)
+
+// Catalog is an immutable snapshot of the course content: chapters and
+// their quiz questions. Callers must not modify the returned slices.
+type Catalog struct {
+	Chapters  []models.Chapter
+	byID      map[int]models.Chapter
+	questions map[int][]models.QuizQuestion
+}
+
+func (c *Catalog) Chapter(id int) (models.Chapter, bool) {
+	ch, ok := c.byID[id]
+	return ch, ok
+}
+
+// Questions returns a chapter's quiz questions in order, or an empty slice.
+func (c *Catalog) Questions(chapterID int) []models.QuizQuestion {
+	if qs, ok := c.questions[chapterID]; ok {
+		return qs
+	}
+	return []models.QuizQuestion{}
+}
+
+var (
+	mu         sync.Mutex
+	current    *Catalog
+	generation uint64
+)
+
+// Get returns the cached catalog, loading it from the database on first
+// use and after any invalidation.
+func Get(ctx context.Context) (*Catalog, error) {
+	mu.Lock()
+	c, gen := current, generation
+	mu.Unlock()
+
+	if c != nil {
+		metrics.CatalogLookup(true)
+		return c, nil
+	}
+	metrics.CatalogLookup(false)
+
+	c, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only cache the snapshot if nothing was written while it loaded;
+	// otherwise it may already be stale and the next call reloads.
+	mu.Lock()
+	if generation == gen {
+		current = c
+	}
+	mu.Unlock()
+
+	return c, nil
+}
+
+// Invalidate drops the cached catalog so the next Get reloads it.
+func Invalidate() {
+	mu.Lock()
+	current = nil
+	generation++
+	mu.Unlock()
+}
+
+// QueryHook invalidates the cache whenever a statement writes to the
+// chapters or quiz_questions tables, so every content change made through
+// database.DB is picked up, including seeding.
+func QueryHook(ctx context.Context, query string) (context.Context, func(error)) {
+	op, table, _ := strings.Cut(metrics.QueryName(query), " ")
+	switch op {
+	case "insert", "update", "delete":
+	default:
+		return ctx, func(error) {}
+	}
+	if table != "chapters" && table != "quiz_questions" {
+		return ctx, func(error) {}
+	}
+	return ctx, func(error) { Invalidate() }
+}
+
+func load(ctx context.Context) (*Catalog, error) {
+	c := &Catalog{
+		Chapters:  []models.Chapter{},
+		byID:      make(map[int]models.Chapter),
+		questions: make(map[int][]models.QuizQuestion),
+	}
+
+	rows, err := database.DB.QueryContext(ctx,
+		"SELECT id, title, description, video_url, order_index FROM chapters ORDER BY order_index",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ch models.Chapter
+		if err := rows.Scan(&ch.ID, &ch.Title, &ch.Description, &ch.VideoURL, &ch.OrderIndex); err != nil {
+			return nil, err
+		}
+		c.Chapters = append(c.Chapters, ch)
+		c.byID[ch.ID] = ch
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	qrows, err := database.DB.QueryContext(ctx,
+		"SELECT id, chapter_id, question_text, options, correct_option, order_index FROM quiz_questions ORDER BY chapter_id, order_index",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer qrows.Close()
+
+	for qrows.Next() {
+		var q models.QuizQuestion
+		var optionsJSON string
+		if err := qrows.Scan(&q.ID, &q.ChapterID, &q.QuestionText, &optionsJSON, &q.CorrectOption, &q.OrderIndex); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(optionsJSON), &q.Options); err != nil {
+			return nil, fmt.Errorf("question %d options: %w", q.ID, err)
+		}
+		c.questions[q.ChapterID] = append(c.questions[q.ChapterID], q)
+	}
+
+	return c, qrows.Err()
+}
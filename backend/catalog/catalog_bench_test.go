@@ -0,0 +1,79 @@
+package catalog_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"resume-learning-backend/catalog"
+	"resume-learning-backend/database"
+	"resume-learning-backend/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+func openSeededDB(b *testing.B) {
+	b.Helper()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := database.InitDB("sqlite", filepath.Join(b.TempDir(), "bench.db")); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { database.CloseDB() })
+	if err := database.SeedData(); err != nil {
+		b.Fatal(err)
+	}
+	catalog.Invalidate()
+}
+
+func serveChapterDetail(b *testing.B) {
+	r := httptest.NewRequest(http.MethodGet, "/api/chapters/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handlers.GetChapterDetail(w, r)
+	if w.Code != http.StatusOK {
+		b.Errorf("GetChapterDetail returned %d: %s", w.Code, w.Body)
+	}
+}
+
+// BenchmarkGetChapterDetail compares chapter detail requests served from a
+// warm catalog with ones that miss every time and read chapters and
+// questions from SQLite, as every request did before the cache.
+func BenchmarkGetChapterDetail(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		openSeededDB(b)
+		serveChapterDetail(b)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			serveChapterDetail(b)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		openSeededDB(b)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			catalog.Invalidate()
+			serveChapterDetail(b)
+		}
+	})
+
+	b.Run("cached-parallel", func(b *testing.B) {
+		openSeededDB(b)
+		serveChapterDetail(b)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				serveChapterDetail(b)
+			}
+		})
+	})
+}
@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxVideoFlushInterval = time.Minute
+	minAdminTokenLength   = 16
+)
+
+type Config struct {
+	ListenAddr           string   `json:"listen_addr"`
+	DBDriver             string   `json:"db_driver"`
+	DBPath               string   `json:"db_path"`
+	CORSOrigins          []string `json:"cors_origins"`
+	CORSMethods          []string `json:"cors_methods"`
+	CORSHeaders          []string `json:"cors_headers"`
+	CORSAllowCredentials bool     `json:"cors_allow_credentials"`
+	Seed                 bool     `json:"seed"`
+	LogLevel             string   `json:"log_level"`
+	TLSCertFile          string   `json:"tls_cert_file"`
+	TLSKeyFile           string   `json:"tls_key_file"`
+	AdminUserIDs         []string `json:"admin_user_ids"`
+	AdminToken           string   `json:"admin_token"`
+	TrustedProxies       []string `json:"trusted_proxies"`
+	VideoFlushInterval   string   `json:"video_flush_interval"`
+
+	TracingExporter string `json:"tracing_exporter"`
+	OTLPEndpoint    string `json:"otlp_endpoint"`
+}
+
+func Default() *Config {
+	return &Config{
+		ListenAddr:   ":8080",
+		DBDriver:     "sqlite",
+		DBPath:       "./learning.db",
+		CORSOrigins:  []string{"http://localhost:*", "http://127.0.0.1:*"},
+		CORSMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		CORSHeaders:  []string{"Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key", "X-Device-ID", "X-Device-Name", "If-None-Match", "X-Admin-Token"},
+		Seed:         true,
+		LogLevel:     "info",
+		AdminUserIDs: []string{},
+
+		TrustedProxies: []string{},
+
+		VideoFlushInterval: "5s",
+
+		TracingExporter: "none",
+	}
+}
+
+// Load builds the effective configuration. Later sources override earlier
+// ones: built-in defaults, then the JSON config file, then environment
+// variables, then command-line flags.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON config file")
+	addr := fs.String("addr", "", "listen address")
+	dbDriver := fs.String("db-driver", "", "database driver")
+	dbPath := fs.String("db-path", "", "database path or DSN")
+	corsOrigins := fs.String("cors-origins", "", "comma-separated allowed CORS origins; * may stand for any subdomain, at any depth, or a port")
+	corsMethods := fs.String("cors-methods", "", "comma-separated allowed CORS methods")
+	corsHeaders := fs.String("cors-headers", "", "comma-separated allowed CORS request headers")
+	corsCreds := fs.Bool("cors-allow-credentials", false, "allow credentialed CORS requests")
+	seed := fs.Bool("seed", true, "seed sample content on startup")
+	logLevel := fs.String("log-level", "", "log level: debug, info, warn or error")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file")
+	tlsKey := fs.String("tls-key", "", "TLS private key file")
+	adminUsers := fs.String("admin-users", "", "comma-separated admin user IDs")
+	adminToken := fs.String("admin-token", "", "secret admins must send in the X-Admin-Token header; required with admin users")
+	trustedProxies := fs.String("trusted-proxies", "", "comma-separated proxy IPs or CIDR ranges whose X-Forwarded-For and X-Real-IP headers are trusted")
+	videoFlush := fs.String("video-flush-interval", "", "how long video heartbeats are buffered before being written, e.g. 5s; 0 writes immediately")
+	tracingExporter := fs.String("tracing-exporter", "", "trace exporter: none, stdout or otlp")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "OTLP/HTTP collector endpoint, e.g. localhost:4318")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		// Unknown keys are rejected so a misspelled setting fails loudly
+		// instead of silently leaving the default in place.
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("parsing config file: %w", err)
+		}
+		if dec.More() {
+			return nil, errors.New("parsing config file: unexpected data after the JSON object")
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.ListenAddr = *addr
+		case "db-driver":
+			cfg.DBDriver = *dbDriver
+		case "db-path":
+			cfg.DBPath = *dbPath
+		case "cors-origins":
+			cfg.CORSOrigins = splitList(*corsOrigins)
+		case "cors-methods":
+			cfg.CORSMethods = splitList(*corsMethods)
+		case "cors-headers":
+			cfg.CORSHeaders = splitList(*corsHeaders)
+		case "cors-allow-credentials":
+			cfg.CORSAllowCredentials = *corsCreds
+		case "seed":
+			cfg.Seed = *seed
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "tls-cert":
+			cfg.TLSCertFile = *tlsCert
+		case "tls-key":
+			cfg.TLSKeyFile = *tlsKey
+		case "admin-users":
+			cfg.AdminUserIDs = splitList(*adminUsers)
+		case "admin-token":
+			cfg.AdminToken = *adminToken
+		case "trusted-proxies":
+			cfg.TrustedProxies = splitList(*trustedProxies)
+		case "video-flush-interval":
+			cfg.VideoFlushInterval = *videoFlush
+		case "tracing-exporter":
+			cfg.TracingExporter = *tracingExporter
+		case "otlp-endpoint":
+			cfg.OTLPEndpoint = *otlpEndpoint
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) applyEnv() error {
+	if v, ok := os.LookupEnv("LISTEN_ADDR"); ok {
+		c.ListenAddr = v
+	}
+	if v, ok := os.LookupEnv("DB_DRIVER"); ok {
+		c.DBDriver = v
+	}
+	if v, ok := os.LookupEnv("DB_PATH"); ok {
+		c.DBPath = v
+	}
+	if v, ok := os.LookupEnv("CORS_ORIGINS"); ok {
+		c.CORSOrigins = splitList(v)
+	}
+	if v, ok := os.LookupEnv("CORS_METHODS"); ok {
+		c.CORSMethods = splitList(v)
+	}
+	if v, ok := os.LookupEnv("CORS_HEADERS"); ok {
+		c.CORSHeaders = splitList(v)
+	}
+	if v, ok := os.LookupEnv("CORS_ALLOW_CREDENTIALS"); ok {
+		creds, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS value %q", v)
+		}
+		c.CORSAllowCredentials = creds
+	}
+	if v, ok := os.LookupEnv("SEED"); ok {
+		seed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid SEED value %q", v)
+		}
+		c.Seed = seed
+	}
+	if v, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		c.LogLevel = v
+	}
+	if v, ok := os.LookupEnv("TLS_CERT_FILE"); ok {
+		c.TLSCertFile = v
+	}
+	if v, ok := os.LookupEnv("TLS_KEY_FILE"); ok {
+		c.TLSKeyFile = v
+	}
+	if v, ok := os.LookupEnv("ADMIN_USER_IDS"); ok {
+		c.AdminUserIDs = splitList(v)
+	}
+	if v, ok := os.LookupEnv("ADMIN_TOKEN"); ok {
+		c.AdminToken = v
+	}
+	if v, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		c.TrustedProxies = splitList(v)
+	}
+	if v, ok := os.LookupEnv("VIDEO_FLUSH_INTERVAL"); ok {
+		c.VideoFlushInterval = v
+	}
+	if v, ok := os.LookupEnv("TRACING_EXPORTER"); ok {
+		c.TracingExporter = v
+	}
+	if v, ok := os.LookupEnv("OTEL_EXPORTER_OTLP_ENDPOINT"); ok {
+		c.OTLPEndpoint = v
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listen address is required"))
+	}
+	if c.DBDriver != "sqlite" {
+		errs = append(errs, fmt.Errorf("unsupported database driver %q", c.DBDriver))
+	}
+	if c.DBPath == "" {
+		errs = append(errs, errors.New("database path is required"))
+	}
+	if len(c.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("at least one CORS origin is required"))
+	}
+	for _, origin := range c.CORSOrigins {
+		if origin == "*" {
+			if c.CORSAllowCredentials {
+				errs = append(errs, errors.New("CORS origin * cannot be combined with allowed credentials"))
+			}
+			continue
+		}
+		if err := validateOriginPattern(origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, header := range c.CORSHeaders {
+		if header == "*" && c.CORSAllowCredentials {
+			errs = append(errs, errors.New("CORS header * cannot be combined with allowed credentials"))
+		}
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("invalid log level %q", c.LogLevel))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS requires both a certificate and a key file"))
+	}
+	// Bearer tokens are bare user IDs, so admin access also needs a secret.
+	if len(c.AdminUserIDs) > 0 && c.AdminToken == "" {
+		errs = append(errs, errors.New("admin users require an admin token"))
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < minAdminTokenLength {
+		errs = append(errs, fmt.Errorf("admin token must be at least %d characters", minAdminTokenLength))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("invalid trusted proxy %q: must be an IP address or CIDR range", proxy))
+		}
+	}
+	if d, err := time.ParseDuration(c.VideoFlushInterval); err != nil || d < 0 || d > maxVideoFlushInterval {
+		errs = append(errs, fmt.Errorf("invalid video flush interval %q: must be a duration between 0 and %s", c.VideoFlushInterval, maxVideoFlushInterval))
+	}
+	switch c.TracingExporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.OTLPEndpoint == "" {
+			errs = append(errs, errors.New("otlp tracing exporter requires an endpoint"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid tracing exporter %q", c.TracingExporter))
+	}
+	for _, path := range []string{c.TLSCertFile, c.TLSKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, fmt.Errorf("TLS file: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// VideoFlushDuration returns the validated video flush interval.
+func (c *Config) VideoFlushDuration() time.Duration {
+	d, _ := time.ParseDuration(c.VideoFlushInterval)
+	return d
+}
+
+func (c *Config) String() string {
+	data, _ := json.Marshal(c.redacted())
+	return string(data)
+}
+
+// LogValue keeps the admin token out of the startup config log.
+func (c *Config) LogValue() slog.Value {
+	return slog.AnyValue(c.redacted())
+}
+
+func (c *Config) redacted() Config {
+	redacted := *c
+	if redacted.AdminToken != "" {
+		redacted.AdminToken = "[redacted]"
+	}
+	return redacted
+}
+
+// validateOriginPattern accepts an exact origin such as
+// "https://app.example.com", or one with a single wildcard standing for
+// subdomains ("https://*.example.com", which also matches nested ones such
+// as "https://a.b.example.com") or a port ("http://localhost:*").
+func validateOriginPattern(origin string) error {
+	invalid := fmt.Errorf("invalid CORS origin %q", origin)
+
+	placeholder := "wildcard"
+	if strings.HasSuffix(origin, ":*") {
+		placeholder = "1"
+	}
+
+	u, err := url.Parse(strings.Replace(origin, "*", placeholder, 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return invalid
+	}
+
+	switch strings.Count(origin, "*") {
+	case 0:
+		return nil
+	case 1:
+		host := strings.TrimPrefix(origin, u.Scheme+"://")
+		if strings.HasPrefix(host, "*.") && strings.Count(host, ".") >= 2 {
+			return nil
+		}
+		if strings.HasSuffix(host, ":*") && !strings.Contains(strings.TrimSuffix(host, ":*"), "*") {
+			return nil
+		}
+	}
+
+	return invalid
+}
+
+func splitList(v string) []string {
+	items := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateOriginPattern(t *testing.T) {
+	tests := []struct {
+		origin string
+		valid  bool
+	}{
+		{"https://app.example.com", true},
+		{"http://localhost:8080", true},
+		{"http://localhost:*", true},
+		{"http://127.0.0.1:*", true},
+		{"https://*.example.com", true},
+		{"https://*.example.com:8443", true},
+
+		{"app.example.com", false},
+		{"ftp://example.com", false},
+		{"https://", false},
+		{"https://example.com/", false},
+		{"https://example.com/app", false},
+		{"https://example.com?x=1", false},
+		{"https://user@example.com", false},
+		{"https://*.com", false},
+		{"https://app.*.example.com", false},
+		{"https://*.*.example.com", false},
+		{"https://*.example.com:*", false},
+		{"http://*:8080", false},
+		{"http://local*:8080", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			err := validateOriginPattern(tt.origin)
+			if (err == nil) != tt.valid {
+				t.Errorf("validateOriginPattern(%q) = %v, want valid=%v", tt.origin, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateCORSWildcards(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		headers []string
+		creds   bool
+		wantErr string
+	}{
+		{name: "any origin without credentials", origins: []string{"*"}},
+		{name: "any origin with credentials", origins: []string{"*"}, creds: true, wantErr: "CORS origin * cannot be combined with allowed credentials"},
+		{name: "port wildcard with credentials", origins: []string{"http://localhost:*"}, creds: true},
+		{name: "any header with credentials", origins: []string{"http://localhost:*"}, headers: []string{"*"}, creds: true, wantErr: "CORS header * cannot be combined with allowed credentials"},
+		{name: "no origins", origins: []string{}, wantErr: "at least one CORS origin is required"},
+		{name: "bad origin", origins: []string{"localhost:3000"}, wantErr: `invalid CORS origin "localhost:3000"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.CORSOrigins = tt.origins
+			if tt.headers != nil {
+				cfg.CORSHeaders = tt.headers
+			}
+			cfg.CORSAllowCredentials = tt.creds
+
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAdminToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		users   []string
+		token   string
+		wantErr string
+	}{
+		{name: "no admins"},
+		{name: "admins with token", users: []string{"admin"}, token: "0123456789abcdef"},
+		{name: "admins without token", users: []string{"admin"}, wantErr: "admin users require an admin token"},
+		{name: "short token", users: []string{"admin"}, token: "secret", wantErr: "admin token must be at least 16 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.AdminUserIDs = tt.users
+			cfg.AdminToken = tt.token
+
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogRedactsAdminToken(t *testing.T) {
+	cfg := Default()
+	cfg.AdminToken = "0123456789abcdef"
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("Effective config", "config", cfg)
+	if strings.Contains(buf.String(), cfg.AdminToken) {
+		t.Errorf("config log leaks the admin token: %s", buf.String())
+	}
+	if s := cfg.String(); strings.Contains(s, cfg.AdminToken) {
+		t.Errorf("String() leaks the admin token: %s", s)
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.2", "10.0.0.0/8", "2001:db8::1", "2001:db8::/32"} {
+		cfg := Default()
+		cfg.TrustedProxies = []string{proxy}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() with trusted proxy %q = %v, want no error", proxy, err)
+		}
+	}
+	for _, proxy := range []string{"proxy.internal", "10.0.0.0/33", "10.0.0.2:80"} {
+		cfg := Default()
+		cfg.TrustedProxies = []string{proxy}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid trusted proxy") {
+			t.Errorf("Validate() with trusted proxy %q = %v, want invalid trusted proxy error", proxy, err)
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"listen_addr": ":7000",
+		"db_path": "/tmp/file.db",
+		"log_level": "warn",
+		"cors_origins": ["https://file.example.com"]
+	}`)
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_PATH", "/tmp/env.db")
+	t.Setenv("LOG_LEVEL", "error")
+
+	cfg, err := Load([]string{"-config", path, "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+
+	checks := []struct {
+		setting, got, want string
+	}{
+		{"listen_addr (file over default)", cfg.ListenAddr, ":7000"},
+		{"db_path (env over file)", cfg.DBPath, "/tmp/env.db"},
+		{"log_level (flag over env)", cfg.LogLevel, "debug"},
+		{"cors_origins (file)", strings.Join(cfg.CORSOrigins, ","), "https://file.example.com"},
+		{"db_driver (default)", cfg.DBDriver, "sqlite"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.setting, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, `{"listen_addr": ":7001"}`))
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if cfg.ListenAddr != ":7001" {
+		t.Errorf("listen_addr = %q, want %q", cfg.ListenAddr, ":7001")
+	}
+}
+
+func TestLoadRejectsBadConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{name: "misspelled key", contents: `{"cors_allowed_origins": ["https://app.example.com"]}`, wantErr: `unknown field "cors_allowed_origins"`},
+		{name: "wrong type", contents: `{"seed": "yes"}`, wantErr: "parsing config file"},
+		{name: "trailing data", contents: `{"seed": false} {"seed": true}`, wantErr: "unexpected data after the JSON object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			_, err := Load([]string{"-config", writeConfigFile(t, tt.contents)})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
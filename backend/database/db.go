@@ -1,20 +1,32 @@
 package database
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
+var DB *InstrumentedDB
 
-func InitDB(dbPath string) error {
-	var err error
-	DB, err = sql.Open("sqlite", dbPath)
+// connectionPragmas are applied to every pooled connection. WAL lets a
+// long-running read, such as a streamed progress export, coexist with
+// learners' writes instead of failing them with SQLITE_BUSY, and the busy
+// timeout makes concurrent writers wait their turn rather than error out.
+var connectionPragmas = []string{
+	"busy_timeout(5000)",
+	"journal_mode(WAL)",
+}
+
+func InitDB(driver, dbPath string) error {
+	conn, err := sql.Open(driver, withPragmas(dbPath))
 	if err != nil {
 		return err
 	}
+	DB = &InstrumentedDB{DB: conn}
 
 	if err = DB.Ping(); err != nil {
 		return err
@@ -24,10 +36,28 @@ func InitDB(dbPath string) error {
 		return err
 	}
 
-	log.Println("Database initialized successfully")
+	if err = migrate(); err != nil {
+		return err
+	}
+
+	slog.Info("Database initialized")
 	return nil
 }
 
+// withPragmas appends connectionPragmas to the DSN as _pragma parameters,
+// which the driver runs on each new connection.
+func withPragmas(dsn string) string {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	for _, p := range connectionPragmas {
+		dsn += sep + "_pragma=" + p
+		sep = "&"
+	}
+	return dsn
+}
+
 func createTables() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS users (
@@ -73,8 +103,195 @@ func createTables() error {
 	return err
 }
 
-func CloseDB() {
+var migrations = []string{
+	`ALTER TABLE user_progress ADD COLUMN quiz_answer_times TEXT DEFAULT '[]'`,
+	`ALTER TABLE user_progress ADD COLUMN video_duration REAL DEFAULT 0`,
+	`ALTER TABLE user_progress ADD COLUMN video_max_timestamp REAL DEFAULT 0`,
+	`ALTER TABLE user_progress ADD COLUMN started_at DATETIME`,
+	`ALTER TABLE user_progress ADD COLUMN client_sequence INTEGER DEFAULT 0`,
+	`CREATE TABLE idempotency_keys (
+		user_id TEXT NOT NULL,
+		key TEXT NOT NULL,
+		request_hash TEXT NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		response_body BLOB,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, key)
+	)`,
+	`ALTER TABLE user_progress ADD COLUMN quiz_answer_clock TEXT DEFAULT '[]'`,
+	`CREATE TABLE device_progress (
+		user_id TEXT NOT NULL,
+		device_id TEXT NOT NULL,
+		device_name TEXT NOT NULL DEFAULT '',
+		chapter_id INTEGER NOT NULL,
+		content_type TEXT NOT NULL,
+		video_timestamp REAL DEFAULT 0,
+		quiz_question_index INTEGER DEFAULT 0,
+		completed BOOLEAN DEFAULT FALSE,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, device_id, chapter_id, content_type)
+	)`,
+	searchIndexMigration,
+	captionsMigration,
+	`CREATE TABLE notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		chapter_id INTEGER NOT NULL,
+		video_timestamp REAL NOT NULL DEFAULT 0,
+		body TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id),
+		FOREIGN KEY (chapter_id) REFERENCES chapters(id)
+	);
+	CREATE INDEX idx_notes_user_chapter ON notes (user_id, chapter_id, video_timestamp)`,
+	progressSequencesMigration,
+}
+
+// searchIndexMigration creates the full-text index behind /api/search and
+// the triggers that keep it in step with chapters and quiz questions. Each
+// row is one searchable document: kind says what it is, ref_id its ID in
+// the source table.
+const searchIndexMigration = `
+	CREATE VIRTUAL TABLE search_index USING fts5(
+		kind UNINDEXED, chapter_id UNINDEXED, ref_id UNINDEXED, title, body,
+		tokenize = 'porter unicode61'
+	);
+
+	INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		SELECT 'chapter', id, id, title, COALESCE(description, '') FROM chapters;
+	INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		SELECT 'question', chapter_id, id, '', question_text FROM quiz_questions;
+
+	CREATE TRIGGER chapters_search_insert AFTER INSERT ON chapters BEGIN
+		INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		VALUES ('chapter', new.id, new.id, new.title, COALESCE(new.description, ''));
+	END;
+	CREATE TRIGGER chapters_search_update AFTER UPDATE ON chapters BEGIN
+		DELETE FROM search_index WHERE kind = 'chapter' AND ref_id = old.id;
+		INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		VALUES ('chapter', new.id, new.id, new.title, COALESCE(new.description, ''));
+	END;
+	CREATE TRIGGER chapters_search_delete AFTER DELETE ON chapters BEGIN
+		DELETE FROM search_index WHERE kind = 'chapter' AND ref_id = old.id;
+	END;
+
+	CREATE TRIGGER quiz_questions_search_insert AFTER INSERT ON quiz_questions BEGIN
+		INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		VALUES ('question', new.chapter_id, new.id, '', new.question_text);
+	END;
+	CREATE TRIGGER quiz_questions_search_update AFTER UPDATE ON quiz_questions BEGIN
+		DELETE FROM search_index WHERE kind = 'question' AND ref_id = old.id;
+		INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		VALUES ('question', new.chapter_id, new.id, '', new.question_text);
+	END;
+	CREATE TRIGGER quiz_questions_search_delete AFTER DELETE ON quiz_questions BEGIN
+		DELETE FROM search_index WHERE kind = 'question' AND ref_id = old.id;
+	END;
+`
+
+func migrate() error {
+	version, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if err := applyMigration(i); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs migration i and records it in user_version in one
+// transaction, so a multi-statement migration that fails part way leaves
+// no trace and is retried whole on the next start.
+func applyMigration(i int) error {
+	tx, err := DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(context.Background(), migrations[i]); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(context.Background(), fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func SchemaVersion() (int, error) {
+	var version int
+	err := DB.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+func CloseDB() error {
 	if DB != nil {
-		DB.Close()
+		return DB.Close()
 	}
+	return nil
 }
+
+// captionsMigration stores WebVTT caption tracks per chapter and language,
+// with their cues parsed out for transcripts. Cue text is added to the
+// search index as "transcript" documents.
+const captionsMigration = `
+	CREATE TABLE captions (
+		chapter_id INTEGER NOT NULL,
+		language TEXT NOT NULL,
+		label TEXT NOT NULL DEFAULT '',
+		vtt TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chapter_id, language),
+		FOREIGN KEY (chapter_id) REFERENCES chapters(id)
+	);
+
+	CREATE TABLE caption_cues (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		chapter_id INTEGER NOT NULL,
+		language TEXT NOT NULL,
+		start_seconds REAL NOT NULL,
+		end_seconds REAL NOT NULL,
+		text TEXT NOT NULL,
+		FOREIGN KEY (chapter_id, language) REFERENCES captions(chapter_id, language)
+	);
+	CREATE INDEX idx_caption_cues_track ON caption_cues (chapter_id, language, start_seconds);
+
+	CREATE TRIGGER caption_cues_search_insert AFTER INSERT ON caption_cues BEGIN
+		INSERT INTO search_index (kind, chapter_id, ref_id, title, body)
+		VALUES ('transcript', new.chapter_id, new.id, '', new.text);
+	END;
+	CREATE TRIGGER caption_cues_search_delete AFTER DELETE ON caption_cues BEGIN
+		DELETE FROM search_index WHERE kind = 'transcript' AND ref_id = old.id;
+	END;
+`
+
+// progressSequencesMigration moves client_sequence tracking from the shared
+// user_progress row to one counter per device, since each device numbers its
+// own saves. Existing sequences carry over to the empty device ID used by
+// clients that don't identify themselves.
+const progressSequencesMigration = `
+	CREATE TABLE progress_sequences (
+		user_id TEXT NOT NULL,
+		device_id TEXT NOT NULL DEFAULT '',
+		chapter_id INTEGER NOT NULL,
+		content_type TEXT NOT NULL,
+		client_sequence INTEGER NOT NULL,
+		PRIMARY KEY (user_id, device_id, chapter_id, content_type)
+	);
+
+	INSERT INTO progress_sequences (user_id, device_id, chapter_id, content_type, client_sequence)
+	SELECT user_id, '', chapter_id, content_type, client_sequence
+	FROM user_progress
+	WHERE client_sequence > 0;
+`
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// QueryHook is called before every statement issued through DB. It may
+// return a derived context for the statement and a function that is called
+// with the statement's error once it has run.
+type QueryHook func(ctx context.Context, query string) (context.Context, func(err error))
+
+var queryHooks []QueryHook
+
+func AddQueryHook(hook QueryHook) {
+	queryHooks = append(queryHooks, hook)
+}
+
+// InstrumentedDB wraps *sql.DB so every query and exec passes through the
+// registered query hooks. All other *sql.DB methods are promoted unchanged.
+type InstrumentedDB struct {
+	*sql.DB
+}
+
+func observe(ctx context.Context, query string) (context.Context, func(error)) {
+	if len(queryHooks) == 0 {
+		return ctx, func(error) {}
+	}
+
+	done := make([]func(error), 0, len(queryHooks))
+	for _, hook := range queryHooks {
+		var finish func(error)
+		ctx, finish = hook(ctx, query)
+		done = append(done, finish)
+	}
+
+	return ctx, func(err error) {
+		for i := len(done) - 1; i >= 0; i-- {
+			done[i](err)
+		}
+	}
+}
+
+func (db *InstrumentedDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *InstrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := observe(ctx, query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (db *InstrumentedDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *InstrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := observe(ctx, query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+func (db *InstrumentedDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *InstrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := observe(ctx, query)
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+// InstrumentedTx wraps *sql.Tx so statements inside a transaction pass
+// through the query hooks like those issued directly on DB.
+type InstrumentedTx struct {
+	*sql.Tx
+}
+
+func (db *InstrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*InstrumentedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &InstrumentedTx{Tx: tx}, nil
+}
+
+func (tx *InstrumentedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := observe(ctx, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (tx *InstrumentedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := observe(ctx, query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+func (tx *InstrumentedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := observe(ctx, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
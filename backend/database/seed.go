@@ -1,7 +1,7 @@
 package database
 
 import (
-	"log"
+	"log/slog"
 )
 
 func SeedData() error {
@@ -12,11 +12,11 @@ func SeedData() error {
 	}
 
 	if count > 0 {
-		log.Println("Database already seeded")
+		slog.Info("Database already seeded")
 		return nil
 	}
 
-	log.Println("Seeding database with sample data...")
+	slog.Info("Seeding database with sample data")
 
 	chapters := []struct {
 		title       string
@@ -94,6 +94,6 @@ func SeedData() error {
 		}
 	}
 
-	log.Println("Database seeded successfully")
+	slog.Info("Database seeded")
 	return nil
 }
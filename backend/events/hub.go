@@ -0,0 +1,105 @@
+package events
+
+import (
+	"sync"
+
+	"resume-learning-backend/models"
+)
+
+const subscriberBuffer = 16
+
+// Hub fans progress changes out to every open stream of the same user. It
+// is in-process only: with several server instances each one only sees the
+// writes it handled itself.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription receives a user's progress events on C. C is closed when the
+// subscription is closed, when the hub shuts down, or when the subscriber
+// falls too far behind; clients should then reconnect and refetch.
+type Subscription struct {
+	C <-chan models.ProgressEvent
+
+	hub    *Hub
+	userID string
+	ch     chan models.ProgressEvent
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[string]map[*Subscription]struct{})}
+}
+
+func (h *Hub) Subscribe(userID string) *Subscription {
+	ch := make(chan models.ProgressEvent, subscriberBuffer)
+	sub := &Subscription{C: ch, hub: h, userID: userID, ch: ch}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+
+	return sub
+}
+
+// Publish delivers ev to the user's subscribers without blocking. A
+// subscriber whose buffer is full is dropped rather than sent a gap.
+func (h *Hub) Publish(userID string, ev models.ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs[userID] {
+		select {
+		case sub.ch <- ev:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Close ends every subscription and rejects new ones. It is called when
+// the server starts shutting down so open streams do not hold up draining.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.remove(s)
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(sub *Subscription) {
+	subs, ok := h.subs[sub.userID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.userID)
+	}
+	close(sub.ch)
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/catalog"
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+
+	"github.com/gorilla/mux"
+)
+
+type learnerQuizAnswers struct {
+	answers []int
+	times   []float64
+	score   int
+}
+
+func GetQuizAnalytics(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	chapterID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid chapter ID")
+		return
+	}
+
+	cat, err := catalog.Get(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch chapter", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch chapter")
+		return
+	}
+	chapter, ok := cat.Chapter(chapterID)
+	if !ok {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Chapter not found")
+		return
+	}
+
+	response := models.QuizAnalyticsResponse{ChapterID: chapterID, ChapterTitle: chapter.Title}
+	questions := cat.Questions(chapterID)
+
+	learners, err := getLearnerQuizAnswers(r.Context(), chapterID, questions)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch quiz answers", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch quiz answers")
+		return
+	}
+
+	response.Learners = len(learners)
+	response.Questions = analyzeQuestions(questions, learners)
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func getLearnerQuizAnswers(ctx context.Context, chapterID int, questions []models.QuizQuestion) ([]learnerQuizAnswers, error) {
+	rows, err := database.DB.QueryContext(ctx,
+		"SELECT user_id, quiz_answers, COALESCE(quiz_answer_times, '[]') FROM user_progress WHERE chapter_id = ? AND content_type = 'quiz'",
+		chapterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var learners []learnerQuizAnswers
+	for rows.Next() {
+		var userID, answersJSON, timesJSON string
+		if err := rows.Scan(&userID, &answersJSON, &timesJSON); err != nil {
+			return nil, err
+		}
+
+		var l learnerQuizAnswers
+		if err := json.Unmarshal([]byte(answersJSON), &l.answers); err != nil {
+			slog.WarnContext(ctx, "Skipping invalid quiz answers", "user_id", userID, "chapter_id", chapterID, "error", err)
+			continue
+		}
+		if err := json.Unmarshal([]byte(timesJSON), &l.times); err != nil {
+			slog.WarnContext(ctx, "Ignoring invalid quiz answer times", "user_id", userID, "chapter_id", chapterID, "error", err)
+		}
+
+		answered := false
+		for i, q := range questions {
+			if i >= len(l.answers) || l.answers[i] < 0 {
+				continue
+			}
+			answered = true
+			if l.answers[i] == q.CorrectOption {
+				l.score++
+			}
+		}
+		if answered {
+			learners = append(learners, l)
+		}
+	}
+
+	return learners, rows.Err()
+}
+
+// analyzeQuestions computes item statistics for each question. The
+// discrimination index is the classic upper/lower 27% split: the correct
+// rate among the highest-scoring learners minus the rate among the lowest.
+func analyzeQuestions(questions []models.QuizQuestion, learners []learnerQuizAnswers) []models.QuestionAnalytics {
+	ranked := make([]learnerQuizAnswers, len(learners))
+	copy(ranked, learners)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	groupSize := int(math.Ceil(float64(len(ranked)) * 0.27))
+	if groupSize > len(ranked)/2 {
+		groupSize = len(ranked) / 2
+	}
+
+	results := make([]models.QuestionAnalytics, 0, len(questions))
+	for i, q := range questions {
+		qa := models.QuestionAnalytics{
+			QuestionID:    q.ID,
+			OrderIndex:    q.OrderIndex,
+			QuestionText:  q.QuestionText,
+			CorrectOption: q.CorrectOption,
+			OptionCounts:  make([]int, len(q.Options)),
+			OptionRates:   make([]float64, len(q.Options)),
+		}
+
+		correct := 0
+		var totalTime float64
+		timed := 0
+		for _, l := range learners {
+			if i >= len(l.answers) || l.answers[i] < 0 || l.answers[i] >= len(q.Options) {
+				continue
+			}
+			qa.Responses++
+			qa.OptionCounts[l.answers[i]]++
+			if l.answers[i] == q.CorrectOption {
+				correct++
+			}
+			if i < len(l.times) && l.times[i] > 0 {
+				totalTime += l.times[i]
+				timed++
+			}
+		}
+
+		if qa.Responses > 0 {
+			qa.CorrectRate = float64(correct) / float64(qa.Responses)
+			for opt, count := range qa.OptionCounts {
+				qa.OptionRates[opt] = float64(count) / float64(qa.Responses)
+			}
+		}
+		if timed > 0 {
+			qa.AvgTimeToAnswer = totalTime / float64(timed)
+		}
+		if groupSize > 0 {
+			upper := correctRate(ranked[:groupSize], i, q.CorrectOption)
+			lower := correctRate(ranked[len(ranked)-groupSize:], i, q.CorrectOption)
+			qa.DiscriminationIndex = upper - lower
+		}
+
+		results = append(results, qa)
+	}
+
+	return results
+}
+
+func correctRate(learners []learnerQuizAnswers, questionIndex, correctOption int) float64 {
+	correct := 0
+	for _, l := range learners {
+		if questionIndex < len(l.answers) && l.answers[questionIndex] == correctOption {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(learners))
+}
+
+func GetVideoAnalytics(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	chapterID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid chapter ID")
+		return
+	}
+
+	buckets := 10
+	if v := r.URL.Query().Get("buckets"); v != "" {
+		buckets, err = strconv.Atoi(v)
+		if err != nil || buckets < 1 || buckets > 100 {
+			apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "buckets must be between 1 and 100")
+			return
+		}
+	}
+
+	cat, err := catalog.Get(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch chapter", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch chapter")
+		return
+	}
+	chapter, ok := cat.Chapter(chapterID)
+	if !ok {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Chapter not found")
+		return
+	}
+
+	response := models.VideoAnalyticsResponse{ChapterID: chapterID, ChapterTitle: chapter.Title}
+
+	positions, duration, err := getFurthestVideoPositions(r.Context(), chapterID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch video progress", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch video progress")
+		return
+	}
+
+	funnel, err := getCompletionFunnel(r.Context(), chapterID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch completion funnel", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch completion funnel")
+		return
+	}
+
+	response.Learners = len(positions)
+	response.Duration = duration
+	response.Funnel = funnel
+	response.DropOff = []models.DropOffPoint{}
+
+	if len(positions) > 0 && duration > 0 {
+		sort.Float64s(positions)
+		response.MedianWatchPosition = median(positions)
+		response.BucketSize = duration / float64(buckets)
+
+		for b := 0; b < buckets; b++ {
+			start := float64(b) * response.BucketSize
+			watching := len(positions) - sort.SearchFloat64s(positions, start)
+			response.DropOff = append(response.DropOff, models.DropOffPoint{
+				Start:     start,
+				Learners:  watching,
+				Retention: float64(watching) / float64(len(positions)) * 100,
+			})
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// getFurthestVideoPositions returns how far each learner got into the
+// chapter video along with the longest duration any client reported. A
+// completed video counts as watched to the end.
+func getFurthestVideoPositions(ctx context.Context, chapterID int) ([]float64, float64, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT
+			MAX(COALESCE(video_max_timestamp, 0), COALESCE(video_timestamp, 0)),
+			COALESCE(video_duration, 0), completed
+		FROM user_progress
+		WHERE chapter_id = ? AND content_type = 'video'
+	`, chapterID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	type watch struct {
+		position  float64
+		completed bool
+	}
+
+	var watches []watch
+	var duration float64
+	for rows.Next() {
+		var wt watch
+		var d float64
+		if err := rows.Scan(&wt.position, &d, &wt.completed); err != nil {
+			return nil, 0, err
+		}
+		duration = math.Max(duration, math.Max(d, wt.position))
+		watches = append(watches, wt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	positions := make([]float64, 0, len(watches))
+	for _, wt := range watches {
+		if wt.completed {
+			wt.position = duration
+		}
+		positions = append(positions, wt.position)
+	}
+
+	return positions, duration, nil
+}
+
+func getCompletionFunnel(ctx context.Context, chapterID int) (models.CompletionFunnel, error) {
+	var funnel models.CompletionFunnel
+	err := database.DB.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN content_type = 'video' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN content_type = 'video' AND completed = 1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN content_type = 'quiz' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN content_type = 'quiz' AND completed = 1 THEN 1 ELSE 0 END), 0)
+		FROM user_progress
+		WHERE chapter_id = ?
+	`, chapterID).Scan(&funnel.VideoStarted, &funnel.VideoCompleted, &funnel.QuizStarted, &funnel.QuizCompleted)
+	return funnel, err
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -4,25 +4,25 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"resume-learning-backend/apierror"
 	"resume-learning-backend/database"
 	"resume-learning-backend/models"
 )
 
 func Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	if req.UserID == "" {
-		http.Error(w, `{"error": "User ID is required"}`, http.StatusBadRequest)
+	if errs := validateLogin(req); len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
 		return
 	}
 
-	_, err := database.DB.Exec("INSERT OR IGNORE INTO users (id) VALUES (?)", req.UserID)
+	_, err := database.DB.ExecContext(r.Context(), "INSERT OR IGNORE INTO users (id) VALUES (?)", req.UserID)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to create user"}`, http.StatusInternalServerError)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to create user")
 		return
 	}
 
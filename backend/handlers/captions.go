@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+	"resume-learning-backend/webvtt"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxCaptionBytes       = 1 << 20
+	maxCaptionLabelLength = 64
+	defaultCaptionLang    = "en"
+)
+
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// canonicalLanguage normalizes a BCP 47 tag's case, e.g. "EN-us" to "en-US",
+// so each track has one spelling.
+func canonicalLanguage(tag string) string {
+	parts := strings.Split(tag, "-")
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2:
+			parts[i] = strings.ToUpper(p)
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func captionURL(chapterID int, language string) string {
+	return fmt.Sprintf("/api/chapters/%d/captions/%s.vtt", chapterID, language)
+}
+
+func ListCaptions(w http.ResponseWriter, r *http.Request) {
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT cap.language, cap.label, strftime('%Y-%m-%dT%H:%M:%SZ', cap.updated_at),
+			(SELECT COUNT(*) FROM caption_cues cc WHERE cc.chapter_id = cap.chapter_id AND cc.language = cap.language)
+		FROM captions cap
+		WHERE cap.chapter_id = ?
+		ORDER BY cap.language
+	`, chapterID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch captions", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch captions")
+		return
+	}
+	defer rows.Close()
+
+	tracks := []models.CaptionTrack{}
+	for rows.Next() {
+		var t models.CaptionTrack
+		var updatedAt string
+		if err := rows.Scan(&t.Language, &t.Label, &updatedAt, &t.Cues); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to scan caption row", "chapter_id", chapterID, "error", err)
+			continue
+		}
+		t.URL = captionURL(chapterID, t.Language)
+		t.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		tracks = append(tracks, t)
+	}
+
+	writeCachedJSON(w, r, tracks, catalogCacheControl)
+}
+
+// GetCaptionFile serves a caption track as uploaded, as text/vtt.
+func GetCaptionFile(w http.ResponseWriter, r *http.Request) {
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+	language := canonicalLanguage(mux.Vars(r)["lang"])
+
+	var vtt string
+	err := database.DB.QueryRowContext(r.Context(),
+		"SELECT vtt FROM captions WHERE chapter_id = ? AND language = ?",
+		chapterID, language,
+	).Scan(&vtt)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Captions not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch captions", "chapter_id", chapterID, "language", language, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch captions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	writeCachedBody(w, r, []byte(vtt), catalogCacheControl)
+}
+
+// GetTranscript returns a caption track's cues as timed plain text, so the
+// app can show a transcript and seek to a line. ?lang= picks the track;
+// without it English is used if present, else the first language.
+func GetTranscript(w http.ResponseWriter, r *http.Request) {
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+
+	language := r.URL.Query().Get("lang")
+	if language != "" {
+		language = canonicalLanguage(language)
+	} else {
+		err := database.DB.QueryRowContext(r.Context(), `
+			SELECT language FROM captions WHERE chapter_id = ?
+			ORDER BY language = ? DESC, language
+			LIMIT 1
+		`, chapterID, defaultCaptionLang).Scan(&language)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			slog.ErrorContext(r.Context(), "Failed to fetch captions", "chapter_id", chapterID, "error", err)
+			apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch transcript")
+			return
+		}
+	}
+
+	cues, found, err := getTranscriptCues(r.Context(), chapterID, language)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch transcript", "chapter_id", chapterID, "language", language, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch transcript")
+		return
+	}
+	if !found {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Transcript not found")
+		return
+	}
+
+	writeCachedJSON(w, r, models.TranscriptResponse{
+		ChapterID: chapterID,
+		Language:  language,
+		Cues:      cues,
+	}, catalogCacheControl)
+}
+
+func getTranscriptCues(ctx context.Context, chapterID int, language string) ([]models.TranscriptCue, bool, error) {
+	var count int
+	err := database.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM captions WHERE chapter_id = ? AND language = ?",
+		chapterID, language,
+	).Scan(&count)
+	if err != nil || count == 0 {
+		return nil, false, err
+	}
+
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT start_seconds, end_seconds, text
+		FROM caption_cues
+		WHERE chapter_id = ? AND language = ?
+		ORDER BY start_seconds, id
+	`, chapterID, language)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+
+	cues := []models.TranscriptCue{}
+	for rows.Next() {
+		var c models.TranscriptCue
+		if err := rows.Scan(&c.Start, &c.End, &c.Text); err != nil {
+			return nil, true, err
+		}
+		cues = append(cues, c)
+	}
+
+	return cues, true, rows.Err()
+}
+
+// UploadCaptions stores the WebVTT file in the request body as the
+// chapter's caption track for {lang}, replacing any existing one. ?label=
+// sets the name shown in the caption picker.
+func UploadCaptions(w http.ResponseWriter, r *http.Request) {
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+
+	language := mux.Vars(r)["lang"]
+	label := strings.TrimSpace(r.URL.Query().Get("label"))
+
+	var errs validationErrors
+	if !languagePattern.MatchString(language) {
+		errs.add("lang", "must be a language tag such as en or pt-BR")
+	}
+	if len(label) > maxCaptionLabelLength {
+		errs.add("label", "must be at most %d characters", maxCaptionLabelLength)
+	}
+	if len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
+		return
+	}
+	language = canonicalLanguage(language)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCaptionBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			apierror.Respond(w, r, http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "Caption file is too large")
+			return
+		}
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeInvalidBody, "Failed to read caption file")
+		return
+	}
+
+	cues, err := webvtt.Parse(body)
+	if err != nil {
+		errs.add("body", "is not valid WebVTT: %v", err)
+	} else if len(cues) == 0 {
+		errs.add("body", "contains no cues")
+	}
+	if len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
+		return
+	}
+
+	if err := saveCaptions(r.Context(), chapterID, language, label, string(body), cues); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to save captions", "chapter_id", chapterID, "language", language, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save captions")
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.CaptionTrack{
+		Language:  language,
+		Label:     label,
+		URL:       captionURL(chapterID, language),
+		Cues:      len(cues),
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	})
+}
+
+func saveCaptions(ctx context.Context, chapterID int, language, label, vtt string, cues []webvtt.Cue) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO captions (chapter_id, language, label, vtt, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(chapter_id, language)
+		DO UPDATE SET label = excluded.label, vtt = excluded.vtt, updated_at = CURRENT_TIMESTAMP
+	`, chapterID, language, label, vtt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM caption_cues WHERE chapter_id = ? AND language = ?", chapterID, language)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cues {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO caption_cues (chapter_id, language, start_seconds, end_seconds, text) VALUES (?, ?, ?, ?, ?)",
+			chapterID, language, c.Start, c.End, c.Text,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func DeleteCaptions(w http.ResponseWriter, r *http.Request) {
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+	language := canonicalLanguage(mux.Vars(r)["lang"])
+
+	deleted, err := deleteCaptions(r.Context(), chapterID, language)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete captions", "chapter_id", chapterID, "language", language, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete captions")
+		return
+	}
+	if !deleted {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Captions not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Captions deleted",
+	})
+}
+
+func deleteCaptions(ctx context.Context, chapterID int, language string) (bool, error) {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM caption_cues WHERE chapter_id = ? AND language = ?", chapterID, language)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM captions WHERE chapter_id = ? AND language = ?", chapterID, language)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -1,89 +1,83 @@
 package handlers
 
 import (
-	"encoding/json"
+	"log/slog"
 	"net/http"
 	"strconv"
 
-	"resume-learning-backend/database"
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/catalog"
 	"resume-learning-backend/models"
 
 	"github.com/gorilla/mux"
 )
 
 func GetChapters(w http.ResponseWriter, r *http.Request) {
-	rows, err := database.DB.Query(
-		"SELECT id, title, description, video_url, order_index FROM chapters ORDER BY order_index",
-	)
+	params, err := parseListParams(r, []string{sortOrder, sortTitle}, false)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch chapters"}`, http.StatusInternalServerError)
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
 		return
 	}
-	defer rows.Close()
 
-	var chapters []models.Chapter
-	for rows.Next() {
-		var ch models.Chapter
-		if err := rows.Scan(&ch.ID, &ch.Title, &ch.Description, &ch.VideoURL, &ch.OrderIndex); err != nil {
-			continue
-		}
-		chapters = append(chapters, ch)
-	}
-
-	if chapters == nil {
-		chapters = []models.Chapter{}
+	cat, err := catalog.Get(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch chapters", "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch chapters")
+		return
 	}
 
-	json.NewEncoder(w).Encode(chapters)
+	chapters, next := pageChapters(cat.Chapters, params)
+	writeCachedJSON(w, r, models.ChapterListResponse{Chapters: chapters, NextCursor: next}, catalogCacheControl)
 }
 
 func GetChapterDetail(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	chapterID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, `{"error": "Invalid chapter ID"}`, http.StatusBadRequest)
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid chapter ID")
 		return
 	}
 
-	var chapter models.Chapter
-	err = database.DB.QueryRow(
-		"SELECT id, title, description, video_url, order_index FROM chapters WHERE id = ?",
-		chapterID,
-	).Scan(&chapter.ID, &chapter.Title, &chapter.Description, &chapter.VideoURL, &chapter.OrderIndex)
+	cat, err := catalog.Get(r.Context())
 	if err != nil {
-		http.Error(w, `{"error": "Chapter not found"}`, http.StatusNotFound)
+		slog.ErrorContext(r.Context(), "Failed to fetch chapter", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch chapter")
 		return
 	}
 
-	rows, err := database.DB.Query(
-		"SELECT id, chapter_id, question_text, options, correct_option, order_index FROM quiz_questions WHERE chapter_id = ? ORDER BY order_index",
-		chapterID,
-	)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch questions"}`, http.StatusInternalServerError)
+	chapter, ok := cat.Chapter(chapterID)
+	if !ok {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Chapter not found")
 		return
 	}
-	defer rows.Close()
 
-	var questions []models.QuizQuestion
-	for rows.Next() {
-		var q models.QuizQuestion
-		var optionsJSON string
-		if err := rows.Scan(&q.ID, &q.ChapterID, &q.QuestionText, &optionsJSON, &q.CorrectOption, &q.OrderIndex); err != nil {
-			continue
-		}
-		json.Unmarshal([]byte(optionsJSON), &q.Options)
-		questions = append(questions, q)
+	response := models.ChapterDetailResponse{
+		Chapter:   chapter,
+		Questions: cat.Questions(chapterID),
 	}
 
-	if questions == nil {
-		questions = []models.QuizQuestion{}
+	writeCachedJSON(w, r, response, catalogCacheControl)
+}
+
+// routeChapter resolves the {id} route variable to an existing chapter,
+// writing an error response and returning false if there is none.
+func routeChapter(w http.ResponseWriter, r *http.Request) (int, bool) {
+	chapterID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid chapter ID")
+		return 0, false
 	}
 
-	response := models.ChapterDetailResponse{
-		Chapter:   chapter,
-		Questions: questions,
+	cat, err := catalog.Get(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch chapter", "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch chapter")
+		return 0, false
+	}
+	if _, ok := cat.Chapter(chapterID); !ok {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Chapter not found")
+		return 0, false
 	}
 
-	json.NewEncoder(w).Encode(response)
+	return chapterID, true
 }
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// The catalog is the same for every user and rarely changes, so clients
+	// may keep it briefly and revalidate with the ETag afterwards. It is
+	// still private: the routes require Authorization, and a shared cache
+	// must not hand the response to unauthenticated clients.
+	catalogCacheControl = "private, max-age=60, must-revalidate"
+	// Progress is per user and changes constantly; clients must revalidate
+	// every time, which is cheap when nothing changed.
+	progressCacheControl = "private, no-cache"
+)
+
+// writeCachedJSON encodes v with a strong ETag derived from the encoded
+// body. A request whose If-None-Match already names that ETag gets 304 Not
+// Modified without a body.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}, cacheControl string) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(v)
+	writeCachedBody(w, r, body.Bytes(), cacheControl)
+}
+
+// writeCachedBody is writeCachedJSON for an already encoded body; the
+// caller sets Content-Type.
+func writeCachedBody(w http.ResponseWriter, r *http.Request, body []byte, cacheControl string) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
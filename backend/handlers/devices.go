@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/middleware"
+	"resume-learning-backend/models"
+)
+
+const maxResumeAlternatives = 3
+
+// execer is satisfied by both database.DB and a transaction from it.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// deviceProgress is one device's last position in a chapter's video or quiz.
+type deviceProgress struct {
+	chapterID      int
+	contentType    string
+	videoTimestamp float64
+	quizIndex      int
+	completed      bool
+	at             time.Time
+}
+
+// recordDeviceProgress stores where a device last was, independent of the
+// merged per-user row. Positions older than the stored one, as replayed by
+// a late sync, are ignored. Requests without a device ID are not tracked.
+func recordDeviceProgress(ctx context.Context, db execer, userID string, device middleware.Device, p deviceProgress) error {
+	if device.ID == "" {
+		return nil
+	}
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO device_progress (user_id, device_id, device_name, chapter_id, content_type, video_timestamp, quiz_question_index, completed, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, device_id, chapter_id, content_type)
+		DO UPDATE SET device_name = CASE WHEN excluded.device_name != '' THEN excluded.device_name ELSE device_name END,
+			video_timestamp = excluded.video_timestamp,
+			quiz_question_index = excluded.quiz_question_index,
+			completed = excluded.completed,
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at >= device_progress.updated_at
+	`, userID, device.ID, device.Name, p.chapterID, p.contentType, p.videoTimestamp, p.quizIndex, p.completed,
+		p.at.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// getDeviceResumePoints returns the latest unfinished position on each of
+// the user's devices, most recent first. Content completed on any device is
+// skipped so a stale position elsewhere is not offered.
+func getDeviceResumePoints(ctx context.Context, userID string) ([]models.ResumePoint, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT chapter_id, title, content_type, video_timestamp, quiz_question_index, total_questions,
+			device_id, device_name, updated_at
+		FROM (
+			SELECT dp.chapter_id, c.title, dp.content_type,
+				COALESCE(dp.video_timestamp, 0) AS video_timestamp,
+				COALESCE(dp.quiz_question_index, 0) AS quiz_question_index,
+				CASE WHEN dp.content_type = 'quiz'
+					THEN (SELECT COUNT(*) FROM quiz_questions q WHERE q.chapter_id = dp.chapter_id)
+					ELSE 0 END AS total_questions,
+				dp.device_id, dp.device_name,
+				strftime('%Y-%m-%dT%H:%M:%SZ', dp.updated_at) AS updated_at,
+				ROW_NUMBER() OVER (PARTITION BY dp.device_id ORDER BY dp.updated_at DESC) AS rn
+			FROM device_progress dp
+			JOIN chapters c ON c.id = dp.chapter_id
+			LEFT JOIN user_progress up ON up.user_id = dp.user_id
+				AND up.chapter_id = dp.chapter_id AND up.content_type = dp.content_type
+			WHERE dp.user_id = ? AND dp.completed = 0 AND COALESCE(up.completed, 0) = 0
+		)
+		WHERE rn = 1
+		ORDER BY updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []models.ResumePoint
+	for rows.Next() {
+		var p models.ResumePoint
+		var updatedAt string
+		err := rows.Scan(&p.ChapterID, &p.ChapterTitle, &p.ContentType, &p.VideoTimestamp, &p.QuizQuestionIndex,
+			&p.TotalQuestions, &p.DeviceID, &p.DeviceName, &updatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+			p.UpdatedAt = &t
+		}
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+func samePosition(a, b models.ResumePoint) bool {
+	return a.ChapterID == b.ChapterID && a.ContentType == b.ContentType &&
+		a.VideoTimestamp == b.VideoTimestamp && a.QuizQuestionIndex == b.QuizQuestionIndex
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"resume-learning-backend/buildinfo"
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+)
+
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok"})
+}
+
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	response := models.ReadinessResponse{
+		Status: "ready",
+		Checks: map[string]string{},
+	}
+
+	fail := func(check, reason string) {
+		response.Status = "not_ready"
+		response.Checks[check] = reason
+	}
+
+	if err := database.DB.PingContext(ctx); err != nil {
+		fail("database", err.Error())
+	} else {
+		response.Checks["database"] = "ok"
+
+		version, err := database.SchemaVersion()
+		switch {
+		case err != nil:
+			fail("migrations", err.Error())
+		case version < database.LatestSchemaVersion():
+			fail("migrations", "pending migrations")
+		default:
+			response.Checks["migrations"] = "ok"
+		}
+
+		var chapters int
+		err = database.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM chapters").Scan(&chapters)
+		switch {
+		case err != nil:
+			fail("seed", err.Error())
+		case chapters == 0:
+			fail("seed", "no content loaded")
+		default:
+			response.Checks["seed"] = "ok"
+		}
+	}
+
+	if response.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func Version(w http.ResponseWriter, r *http.Request) {
+	info := buildinfo.Get()
+	schemaVersion, _ := database.SchemaVersion()
+
+	json.NewEncoder(w).Encode(models.VersionResponse{
+		Commit:        info.Commit,
+		BuildTime:     info.BuildTime,
+		GoVersion:     info.GoVersion,
+		SchemaVersion: schemaVersion,
+	})
+}
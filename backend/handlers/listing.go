@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"resume-learning-backend/models"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+	maxSearchLength = 100
+
+	sortOrder  = "order"
+	sortTitle  = "title"
+	sortRecent = "recent"
+
+	statusNotStarted = "not-started"
+	statusInProgress = "in-progress"
+	statusCompleted  = "completed"
+)
+
+// listParams are the query parameters shared by the chapter list
+// endpoints: limit, cursor, sort, search and (for progress) status.
+type listParams struct {
+	limit  int
+	sort   string
+	search string
+	status string
+	after  *listCursor
+}
+
+// listCursor holds the sort key and ID of the last item on a page. Clients
+// get it as an opaque next_cursor string and pass it back as cursor.
+type listCursor struct {
+	Sort     string `json:"s"`
+	Order    int    `json:"o,omitempty"`
+	Title    string `json:"t,omitempty"`
+	Activity string `json:"a,omitempty"`
+	ID       int    `json:"id"`
+}
+
+func (c listCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(s string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// parseListParams reads list query parameters, accepting only the given
+// sort orders and, when withStatus is set, a status filter.
+func parseListParams(r *http.Request, sorts []string, withStatus bool) (listParams, error) {
+	q := r.URL.Query()
+	p := listParams{limit: defaultPageSize, sort: sortOrder}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		p.limit = limit
+	}
+
+	if v := q.Get("sort"); v != "" {
+		p.sort = v
+	}
+	valid := false
+	for _, s := range sorts {
+		valid = valid || s == p.sort
+	}
+	if !valid {
+		return p, fmt.Errorf("sort must be one of %s", strings.Join(sorts, ", "))
+	}
+
+	p.search = strings.TrimSpace(q.Get("search"))
+	if len(p.search) > maxSearchLength {
+		return p, fmt.Errorf("search must be at most %d characters", maxSearchLength)
+	}
+
+	if v := q.Get("status"); v != "" {
+		if !withStatus {
+			return p, errors.New("status filter is not supported here")
+		}
+		switch v {
+		case statusNotStarted, statusInProgress, statusCompleted:
+			p.status = v
+		default:
+			return p, fmt.Errorf("status must be one of %s, %s, %s", statusNotStarted, statusInProgress, statusCompleted)
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeListCursor(v)
+		if err != nil || c.Sort != p.sort {
+			return p, errors.New("cursor is invalid or was issued for a different sort")
+		}
+		p.after = c
+	}
+
+	return p, nil
+}
+
+// likePattern turns search text into a LIKE pattern matching it anywhere,
+// with LIKE wildcards in the text escaped by a backslash.
+func likePattern(search string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(search) + "%"
+}
+
+// pageChapters filters, sorts and pages an in-memory chapter list, such as
+// the cached catalog, with the same semantics as the SQL-backed lists.
+func pageChapters(chapters []models.Chapter, p listParams) ([]models.Chapter, string) {
+	search := strings.ToLower(p.search)
+	matched := make([]models.Chapter, 0, len(chapters))
+	for _, ch := range chapters {
+		if search == "" ||
+			strings.Contains(strings.ToLower(ch.Title), search) ||
+			strings.Contains(strings.ToLower(ch.Description), search) {
+			matched = append(matched, ch)
+		}
+	}
+
+	cursorOf := func(ch models.Chapter) listCursor {
+		if p.sort == sortTitle {
+			return listCursor{Sort: sortTitle, Title: strings.ToLower(ch.Title), ID: ch.ID}
+		}
+		return listCursor{Sort: sortOrder, Order: ch.OrderIndex, ID: ch.ID}
+	}
+	less := func(a, b listCursor) bool {
+		switch {
+		case a.Title != b.Title:
+			return a.Title < b.Title
+		case a.Order != b.Order:
+			return a.Order < b.Order
+		}
+		return a.ID < b.ID
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool { return less(cursorOf(matched[i]), cursorOf(matched[j])) })
+
+	start := 0
+	if p.after != nil {
+		start = sort.Search(len(matched), func(i int) bool { return less(*p.after, cursorOf(matched[i])) })
+	}
+
+	page := matched[start:]
+	if len(page) <= p.limit {
+		return page, ""
+	}
+	page = page[:p.limit]
+	return page, cursorOf(page[len(page)-1]).encode()
+}
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/database"
+	"resume-learning-backend/middleware"
+	"resume-learning-backend/models"
+
+	"github.com/gorilla/mux"
+)
+
+const noteColumns = `id, chapter_id, video_timestamp, body,
+	strftime('%Y-%m-%dT%H:%M:%SZ', created_at), strftime('%Y-%m-%dT%H:%M:%SZ', updated_at)`
+
+func scanNote(row interface{ Scan(...interface{}) error }) (models.Note, error) {
+	var n models.Note
+	var createdAt, updatedAt string
+	if err := row.Scan(&n.ID, &n.ChapterID, &n.VideoTimestamp, &n.Body, &createdAt, &updatedAt); err != nil {
+		return n, err
+	}
+	n.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	n.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return n, nil
+}
+
+// ListNotes returns the user's notes and bookmarks for a chapter in video
+// order.
+func ListNotes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT `+noteColumns+`
+		FROM notes
+		WHERE user_id = ? AND chapter_id = ?
+		ORDER BY video_timestamp, id
+	`, userID, chapterID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch notes", "user_id", userID, "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch notes")
+		return
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to scan note row", "user_id", userID, "error", err)
+			continue
+		}
+		notes = append(notes, n)
+	}
+
+	json.NewEncoder(w).Encode(notes)
+}
+
+func CreateNote(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	chapterID, ok := routeChapter(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.NoteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if errs := validateNote(req); len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
+		return
+	}
+
+	note, err := scanNote(database.DB.QueryRowContext(r.Context(), `
+		INSERT INTO notes (user_id, chapter_id, video_timestamp, body)
+		VALUES (?, ?, ?, ?)
+		RETURNING `+noteColumns,
+		userID, chapterID, req.VideoTimestamp, req.Body,
+	))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to create note", "user_id", userID, "chapter_id", chapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save note")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// routeNoteID parses the {noteID} route variable. Notes are always looked
+// up together with the user ID, so another user's note is "not found".
+func routeNoteID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	noteID, err := strconv.Atoi(mux.Vars(r)["noteID"])
+	if err != nil {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid note ID")
+		return 0, false
+	}
+	return noteID, true
+}
+
+func GetNote(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID, ok := routeNoteID(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := scanNote(database.DB.QueryRowContext(r.Context(),
+		"SELECT "+noteColumns+" FROM notes WHERE id = ? AND user_id = ?",
+		noteID, userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Note not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch note", "user_id", userID, "note_id", noteID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch note")
+		return
+	}
+
+	json.NewEncoder(w).Encode(note)
+}
+
+// UpdateNote replaces a note's timestamp and body.
+func UpdateNote(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID, ok := routeNoteID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.NoteRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if errs := validateNote(req); len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
+		return
+	}
+
+	note, err := scanNote(database.DB.QueryRowContext(r.Context(), `
+		UPDATE notes
+		SET video_timestamp = ?, body = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?
+		RETURNING `+noteColumns,
+		req.VideoTimestamp, req.Body, noteID, userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Note not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to update note", "user_id", userID, "note_id", noteID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save note")
+		return
+	}
+
+	json.NewEncoder(w).Encode(note)
+}
+
+func DeleteNote(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	noteID, ok := routeNoteID(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.ExecContext(r.Context(),
+		"DELETE FROM notes WHERE id = ? AND user_id = ?",
+		noteID, userID,
+	)
+	var n int64
+	if err == nil {
+		n, err = result.RowsAffected()
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete note", "user_id", userID, "note_id", noteID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to delete note")
+		return
+	}
+	if n == 0 {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Note not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Note deleted",
+	})
+}
+
+// ExportNotes writes all of the user's notes as one Markdown document, with
+// a section per chapter in course order and an entry per note in video
+// order. Bookmarks appear with their timestamp only.
+func ExportNotes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	rows, err := database.DB.QueryContext(r.Context(), `
+		SELECT c.id, c.title, n.video_timestamp, n.body
+		FROM notes n
+		JOIN chapters c ON c.id = n.chapter_id
+		WHERE n.user_id = ?
+		ORDER BY c.order_index, c.id, n.video_timestamp, n.id
+	`, userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch notes", "user_id", userID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to export notes")
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="notes.md"`)
+
+	if err := writeNotesMarkdown(w, rows); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to export notes", "user_id", userID, "error", err)
+	}
+}
+
+func writeNotesMarkdown(w http.ResponseWriter, rows *sql.Rows) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "# Course notes\n")
+
+	lastChapter := 0
+	empty := true
+	for rows.Next() {
+		var chapterID int
+		var title, body string
+		var timestamp float64
+		if err := rows.Scan(&chapterID, &title, &timestamp, &body); err != nil {
+			return err
+		}
+		empty = false
+
+		if chapterID != lastChapter {
+			fmt.Fprintf(bw, "\n## %s\n\n", escapeMarkdownLine(strings.Join(strings.Fields(title), " ")))
+			lastChapter = chapterID
+		}
+
+		fmt.Fprintf(bw, "- **%s**", formatVideoTimestamp(timestamp))
+		body = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(body)
+		lines := strings.Split(strings.TrimSpace(body), "\n")
+		if lines[0] == "" {
+			fmt.Fprint(bw, " Bookmark\n")
+			continue
+		}
+		fmt.Fprintf(bw, " %s\n", escapeMarkdownLine(lines[0]))
+		for _, line := range lines[1:] {
+			fmt.Fprintf(bw, "  %s\n", escapeMarkdownLine(line))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if empty {
+		fmt.Fprint(bw, "\nNo notes yet.\n")
+	}
+
+	return bw.Flush()
+}
+
+// orderedListMarker matches the start of an ordered list item, "1." or "1)".
+var orderedListMarker = regexp.MustCompile(`^[0-9]{1,9}[.)]`)
+
+// escapeMarkdownLine backslash-escapes a leading character that Markdown
+// would read as block structure (a heading, list item, thematic break,
+// quote, fence, table, HTML block or link reference definition), so a
+// learner's text stays inside the note it belongs to. Leading indentation
+// is dropped, since four spaces would start a code block.
+func escapeMarkdownLine(line string) string {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" {
+		return line
+	}
+	if strings.ContainsRune("#-+*=_>`~|<[", rune(line[0])) {
+		return `\` + line
+	}
+	if m := orderedListMarker.FindString(line); m != "" {
+		return m[:len(m)-1] + `\` + m[len(m)-1:] + line[len(m):]
+	}
+	return line
+}
+
+// formatVideoTimestamp renders seconds as m:ss, or h:mm:ss for an hour or
+// more, like a video player's time display.
+func formatVideoTimestamp(seconds float64) string {
+	s := int(seconds)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
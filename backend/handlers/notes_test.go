@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"resume-learning-backend/catalog"
+	"resume-learning-backend/database"
+	"resume-learning-backend/middleware"
+)
+
+func openTestDB(t *testing.T) {
+	t.Helper()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := database.InitDB("sqlite", filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.CloseDB() })
+	if err := database.SeedData(); err != nil {
+		t.Fatal(err)
+	}
+	catalog.Invalidate()
+}
+
+func TestExportNotesEscapesMarkdown(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+
+	if _, err := database.DB.ExecContext(ctx, "UPDATE chapters SET title = ? WHERE id = 1", "Intro\n# Injected"); err != nil {
+		t.Fatal(err)
+	}
+	body := "# Not a heading\n---\n[ref]: https://example.com\n> quoted\n1. numbered\n    indented\nplain *emphasis*"
+	if _, err := database.DB.ExecContext(ctx, `
+		INSERT INTO notes (user_id, chapter_id, video_timestamp, body) VALUES
+			('u1', 1, 70, ?),
+			('u1', 1, 272, '')
+	`, body); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/notes/export.md", nil)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, "u1"))
+	w := httptest.NewRecorder()
+	ExportNotes(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+
+	want := "# Course notes\n" +
+		"\n## Intro # Injected\n\n" +
+		"- **1:10** \\# Not a heading\n" +
+		"  \\---\n" +
+		"  \\[ref]: https://example.com\n" +
+		"  \\> quoted\n" +
+		"  1\\. numbered\n" +
+		"  indented\n" +
+		"  plain *emphasis*\n" +
+		"- **4:32** Bookmark\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("export:\n%s\nwant:\n%s", got, want)
+	}
+}
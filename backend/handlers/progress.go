@@ -1,46 +1,75 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
+	"resume-learning-backend/apierror"
 	"resume-learning-backend/database"
+	"resume-learning-backend/metrics"
 	"resume-learning-backend/middleware"
 	"resume-learning-backend/models"
+	"resume-learning-backend/reporting"
+	"resume-learning-backend/writebehind"
 )
 
 func GetProgress(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	if userID == "" {
-		http.Error(w, `{"error": "User not authenticated"}`, http.StatusUnauthorized)
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
 		return
 	}
 
-	chapters, err := getChaptersWithProgress(userID)
+	params, err := parseListParams(r, []string{sortOrder, sortTitle, sortRecent}, true)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch progress"}`, http.StatusInternalServerError)
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
 		return
 	}
 
-	resumePoint, _ := getResumePoint(userID)
+	flushPendingVideo(r.Context(), userID)
+
+	chapters, next, err := getChaptersWithProgress(r.Context(), userID, params)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch progress", "user_id", userID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch progress")
+		return
+	}
+
+	resumePoint, err := getResumePoint(r.Context(), userID, middleware.GetDevice(r).ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.ErrorContext(r.Context(), "Failed to compute resume point", "user_id", userID, "error", err)
+	}
 
 	response := models.ProgressResponse{
 		Chapters:    chapters,
+		NextCursor:  next,
 		ResumePoint: resumePoint,
 	}
 
-	json.NewEncoder(w).Encode(response)
+	w.Header().Add("Vary", middleware.DeviceIDHeader)
+	writeCachedJSON(w, r, response, progressCacheControl)
 }
 
 func GetResumePoint(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	if userID == "" {
-		http.Error(w, `{"error": "User not authenticated"}`, http.StatusUnauthorized)
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
 		return
 	}
 
-	resumePoint, err := getResumePoint(userID)
+	flushPendingVideo(r.Context(), userID)
+
+	resumePoint, err := getResumePoint(r.Context(), userID, middleware.GetDevice(r).ID)
 	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.ErrorContext(r.Context(), "Failed to compute resume point", "user_id", userID, "error", err)
+		}
 		json.NewEncoder(w).Encode(map[string]interface{}{"resume_point": nil})
 		return
 	}
@@ -51,30 +80,77 @@ func GetResumePoint(w http.ResponseWriter, r *http.Request) {
 func SaveVideoProgress(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	if userID == "" {
-		http.Error(w, `{"error": "User not authenticated"}`, http.StatusUnauthorized)
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req models.VideoProgressRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	_, err := database.DB.Exec(`
-		INSERT INTO user_progress (user_id, chapter_id, content_type, video_timestamp, completed, updated_at)
-		VALUES (?, ?, 'video', ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(user_id, chapter_id, content_type) 
-		DO UPDATE SET video_timestamp = ?, completed = ?, updated_at = CURRENT_TIMESTAMP
-	`, userID, req.ChapterID, req.Timestamp, req.Completed, req.Timestamp, req.Completed)
-
+	errs, err := validateVideoProgress(r.Context(), req)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to save progress"}`, http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to validate video progress",
+			"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save progress")
+		return
+	}
+	if len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
 		return
 	}
 
+	device := middleware.GetDevice(r)
+	now := time.Now()
+
+	var applied bool
+	if videoBuffer != nil && !req.Completed {
+		// Check the stored sequence now, so a save acknowledged as applied
+		// is not later dropped as stale when the buffer is flushed.
+		var stale bool
+		stale, err = sequenceIsStale(r.Context(), userID, device.ID, req.ChapterID, "video", req.ClientSequence)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to check video progress sequence",
+				"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+			apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save progress")
+			return
+		}
+		applied = !stale && videoBuffer.Put(writebehind.VideoWrite{
+			UserID:     userID,
+			DeviceID:   device.ID,
+			DeviceName: device.Name,
+			Request:    req,
+			At:         now,
+		})
+	} else {
+		applied, err = saveVideoProgressNow(r.Context(), userID, device, req, now)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to save video progress",
+				"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+			apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save progress")
+			return
+		}
+	}
+
+	if !applied {
+		json.NewEncoder(w).Encode(staleWriteResponse)
+		return
+	}
+
+	publishProgress(userID, models.ProgressEvent{
+		ChapterID:      req.ChapterID,
+		ContentType:    "video",
+		VideoTimestamp: req.Timestamp,
+		Completed:      req.Completed,
+		DeviceID:       device.ID,
+		UpdatedAt:      now,
+	})
+	metrics.VideoSaved(req.ChapterID, req.Completed)
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
+		"applied": true,
 		"message": "Video progress saved",
 	})
 }
@@ -82,126 +158,388 @@ func SaveVideoProgress(w http.ResponseWriter, r *http.Request) {
 func SaveQuizProgress(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r)
 	if userID == "" {
-		http.Error(w, `{"error": "User not authenticated"}`, http.StatusUnauthorized)
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	var req models.QuizProgressRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	answersJSON, _ := json.Marshal(req.Answers)
-
-	_, err := database.DB.Exec(`
-		INSERT INTO user_progress (user_id, chapter_id, content_type, quiz_question_index, quiz_answers, completed, updated_at)
-		VALUES (?, ?, 'quiz', ?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(user_id, chapter_id, content_type) 
-		DO UPDATE SET quiz_question_index = ?, quiz_answers = ?, completed = ?, updated_at = CURRENT_TIMESTAMP
-	`, userID, req.ChapterID, req.QuestionIndex, string(answersJSON), req.Completed,
-		req.QuestionIndex, string(answersJSON), req.Completed)
+	errs, err := validateQuizProgress(r.Context(), req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to validate quiz progress",
+			"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save progress")
+		return
+	}
+	if len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
+		return
+	}
 
+	device := middleware.GetDevice(r)
+	applied, err := saveQuizProgress(r.Context(), userID, device.ID, req)
 	if err != nil {
-		http.Error(w, `{"error": "Failed to save progress"}`, http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Failed to save quiz progress",
+			"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to save progress")
 		return
 	}
 
+	if !applied {
+		json.NewEncoder(w).Encode(staleWriteResponse)
+		return
+	}
+
+	now := time.Now()
+	err = recordDeviceProgress(r.Context(), database.DB, userID, device, deviceProgress{
+		chapterID:   req.ChapterID,
+		contentType: "quiz",
+		quizIndex:   req.QuestionIndex,
+		completed:   req.Completed,
+		at:          now,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to record device progress",
+			"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+	}
+
+	publishProgress(userID, models.ProgressEvent{
+		ChapterID:         req.ChapterID,
+		ContentType:       "quiz",
+		QuizQuestionIndex: req.QuestionIndex,
+		Completed:         req.Completed,
+		DeviceID:          device.ID,
+		UpdatedAt:         now,
+	})
+	metrics.QuizSubmitted(req.ChapterID, req.Completed)
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
+		"applied": true,
 		"message": "Quiz progress saved",
 	})
 }
 
-func getChaptersWithProgress(userID string) ([]models.ChapterWithProgress, error) {
-	rows, err := database.DB.Query(`
+var staleWriteResponse = map[string]interface{}{
+	"success": true,
+	"applied": false,
+	"message": "Ignored progress older than the saved state",
+}
+
+// claimSequence records seq as the newest client_sequence a device has sent
+// for a progress row and reports false if it already sent a higher one.
+// Sequences are only compared within a device, since each device keeps its
+// own counter, often from its own clock. A zero sequence comes from clients
+// that don't send one and is always accepted.
+func claimSequence(ctx context.Context, db execer, userID, deviceID string, chapterID int, contentType string, seq int64) (bool, error) {
+	if seq == 0 {
+		return true, nil
+	}
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO progress_sequences (user_id, device_id, chapter_id, content_type, client_sequence)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, device_id, chapter_id, content_type)
+		DO UPDATE SET client_sequence = excluded.client_sequence
+		WHERE excluded.client_sequence >= client_sequence
+	`, userID, deviceID, chapterID, contentType, seq)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// sequenceIsStale reports whether the device has already sent a higher
+// client_sequence for the progress row, without recording seq.
+func sequenceIsStale(ctx context.Context, userID, deviceID string, chapterID int, contentType string, seq int64) (bool, error) {
+	if seq == 0 {
+		return false, nil
+	}
+
+	var stored int64
+	err := database.DB.QueryRowContext(ctx, `
+		SELECT client_sequence FROM progress_sequences
+		WHERE user_id = ? AND device_id = ? AND chapter_id = ? AND content_type = ?
+	`, userID, deviceID, chapterID, contentType).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return seq < stored, err
+}
+
+// saveVideoProgress upserts a user's video position, raising the furthest
+// position reached to at least maxTimestamp. A save whose client_sequence is
+// lower than one the same device already sent is a stale retry and is
+// skipped; applied reports whether the row was written.
+func saveVideoProgress(ctx context.Context, db execer, userID, deviceID string, req models.VideoProgressRequest, maxTimestamp float64) (bool, error) {
+	fresh, err := claimSequence(ctx, db, userID, deviceID, req.ChapterID, "video", req.ClientSequence)
+	if err != nil || !fresh {
+		return false, err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, chapter_id, content_type, video_timestamp, video_duration, video_max_timestamp, completed, started_at, updated_at)
+		VALUES (?, ?, 'video', ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, chapter_id, content_type)
+		DO UPDATE SET video_timestamp = excluded.video_timestamp,
+			video_duration = MAX(COALESCE(video_duration, 0), excluded.video_duration),
+			video_max_timestamp = MAX(COALESCE(video_max_timestamp, 0), excluded.video_max_timestamp),
+			completed = excluded.completed,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, req.ChapterID, req.Timestamp, req.Duration, maxTimestamp, req.Completed)
+	return err == nil, err
+}
+
+func saveQuizProgress(ctx context.Context, userID, deviceID string, req models.QuizProgressRequest) (bool, error) {
+	answersJSON, _ := json.Marshal(req.Answers)
+	if req.AnswerTimes == nil {
+		req.AnswerTimes = []float64{}
+	}
+	answerTimesJSON, _ := json.Marshal(req.AnswerTimes)
+	answerClockJSON, _ := json.Marshal(answerClock(req.Answers, time.Now()))
+
+	fresh, err := claimSequence(ctx, database.DB, userID, deviceID, req.ChapterID, "quiz", req.ClientSequence)
+	if err != nil || !fresh {
+		return false, err
+	}
+
+	_, err = database.DB.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, chapter_id, content_type, quiz_question_index, quiz_answers, quiz_answer_times, quiz_answer_clock, completed, started_at, updated_at)
+		VALUES (?, ?, 'quiz', ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, chapter_id, content_type)
+		DO UPDATE SET quiz_question_index = excluded.quiz_question_index,
+			quiz_answers = excluded.quiz_answers,
+			quiz_answer_times = excluded.quiz_answer_times,
+			quiz_answer_clock = excluded.quiz_answer_clock,
+			completed = excluded.completed,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, req.ChapterID, req.QuestionIndex, string(answersJSON), string(answerTimesJSON), string(answerClockJSON), req.Completed)
+	return err == nil, err
+}
+
+// answerClock records when each answer was given, in Unix milliseconds, so
+// offline sync can merge answers per question. Unanswered questions get 0 so
+// any later answer fills them.
+func answerClock(answers []int, at time.Time) []int64 {
+	clock := make([]int64, len(answers))
+	for i, answer := range answers {
+		if answer >= 0 {
+			clock[i] = at.UnixMilli()
+		}
+	}
+	return clock
+}
+
+// getChaptersWithProgress returns one page of chapters with the user's
+// progress, filtered and sorted by p, and the cursor for the next page.
+func getChaptersWithProgress(ctx context.Context, userID string, p listParams) ([]models.ChapterWithProgress, string, error) {
+	const lastActivity = "MAX(COALESCE(vp.updated_at, ''), COALESCE(qp.updated_at, ''))"
+
+	var where []string
+	args := []interface{}{userID, userID}
+
+	switch p.status {
+	case statusNotStarted:
+		where = append(where, "vp.id IS NULL AND qp.id IS NULL")
+	case statusCompleted:
+		where = append(where, "COALESCE(vp.completed, 0) = 1 AND COALESCE(qp.completed, 0) = 1")
+	case statusInProgress:
+		where = append(where, "(vp.id IS NOT NULL OR qp.id IS NOT NULL) AND NOT (COALESCE(vp.completed, 0) = 1 AND COALESCE(qp.completed, 0) = 1)")
+	}
+
+	if p.search != "" {
+		pattern := likePattern(p.search)
+		where = append(where, `(c.title LIKE ? ESCAPE '\' OR c.description LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+
+	var orderBy string
+	switch p.sort {
+	case sortTitle:
+		orderBy = "LOWER(c.title), c.id"
+		if p.after != nil {
+			where = append(where, "(LOWER(c.title) > ? OR (LOWER(c.title) = ? AND c.id > ?))")
+			args = append(args, p.after.Title, p.after.Title, p.after.ID)
+		}
+	case sortRecent:
+		orderBy = lastActivity + " DESC, c.id"
+		if p.after != nil {
+			where = append(where, "("+lastActivity+" < ? OR ("+lastActivity+" = ? AND c.id > ?))")
+			args = append(args, p.after.Activity, p.after.Activity, p.after.ID)
+		}
+	default:
+		orderBy = "c.order_index, c.id"
+		if p.after != nil {
+			where = append(where, "(c.order_index > ? OR (c.order_index = ? AND c.id > ?))")
+			args = append(args, p.after.Order, p.after.Order, p.after.ID)
+		}
+	}
+
+	query := `
 		SELECT 
 			c.id, c.title, c.description, c.video_url, c.order_index,
 			COALESCE(vp.video_timestamp, 0) as video_timestamp,
 			COALESCE(vp.completed, 0) as video_completed,
 			COALESCE(qp.quiz_question_index, 0) as quiz_index,
-			COALESCE(qp.completed, 0) as quiz_completed
-		FROM chapters c
-		LEFT JOIN user_progress vp ON c.id = vp.chapter_id AND vp.user_id = ? AND vp.content_type = 'video'
-		LEFT JOIN user_progress qp ON c.id = qp.chapter_id AND qp.user_id = ? AND qp.content_type = 'quiz'
-		ORDER BY c.order_index
-	`, userID, userID)
+			COALESCE(qp.completed, 0) as quiz_completed,
+			LOWER(c.title) as sort_title,
+			` + lastActivity + ` as last_activity
+		FROM chapters c` + reporting.ChapterProgressJoins("?")
+	if len(where) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	query += "\n\t\tORDER BY " + orderBy + "\n\t\tLIMIT ?"
+	args = append(args, p.limit+1)
+
+	rows, err := database.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
-	var chapters []models.ChapterWithProgress
+	chapters := []models.ChapterWithProgress{}
+	var next string
+	var last listCursor
 	for rows.Next() {
 		var ch models.ChapterWithProgress
 		var videoTimestamp float64
 		var videoCompleted, quizCompleted bool
 		var quizIndex int
+		var sortTitle, activity string
 
 		err := rows.Scan(
 			&ch.ID, &ch.Title, &ch.Description, &ch.VideoURL, &ch.OrderIndex,
-			&videoTimestamp, &videoCompleted, &quizIndex, &quizCompleted,
+			&videoTimestamp, &videoCompleted, &quizIndex, &quizCompleted, &sortTitle, &activity,
 		)
 		if err != nil {
+			slog.ErrorContext(ctx, "Failed to scan chapter progress row", "user_id", userID, "error", err)
 			continue
 		}
 
+		// The extra row fetched beyond the limit only signals another page.
+		if len(chapters) == p.limit {
+			next = last.encode()
+			break
+		}
+
 		ch.VideoProgress = videoTimestamp
 		ch.VideoCompleted = videoCompleted
 		ch.QuizProgress = float64(quizIndex) / 5.0 * 100
 		ch.QuizCompleted = quizCompleted
 
 		chapters = append(chapters, ch)
+		last = listCursor{Sort: p.sort, ID: ch.ID}
+		switch p.sort {
+		case sortTitle:
+			last.Title = sortTitle
+		case sortRecent:
+			last.Activity = activity
+		default:
+			last.Order = ch.OrderIndex
+		}
+	}
+
+	return chapters, next, rows.Err()
+}
+
+// getResumePoint picks up where the given device left off, falling back to
+// the user's most recent activity for new or untracked devices. Newer
+// positions on the user's other devices are offered as alternatives.
+func getResumePoint(ctx context.Context, userID, deviceID string) (*models.ResumePoint, error) {
+	devices, err := getDeviceResumePoints(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch device resume points", "user_id", userID, "error", err)
 	}
 
-	if chapters == nil {
-		chapters = []models.ChapterWithProgress{}
+	var resumePoint *models.ResumePoint
+	var others []models.ResumePoint
+	for _, p := range devices {
+		if deviceID != "" && p.DeviceID == deviceID {
+			current := p
+			resumePoint = &current
+		} else {
+			others = append(others, p)
+		}
+	}
+
+	if resumePoint == nil {
+		resumePoint, err = getLatestResumePoint(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return chapters, nil
+	for _, alt := range others {
+		if len(resumePoint.Alternatives) == maxResumeAlternatives {
+			break
+		}
+		if samePosition(alt, *resumePoint) {
+			continue
+		}
+		if resumePoint.UpdatedAt != nil && alt.UpdatedAt != nil && alt.UpdatedAt.Before(*resumePoint.UpdatedAt) {
+			continue
+		}
+		resumePoint.Alternatives = append(resumePoint.Alternatives, alt)
+	}
+
+	return resumePoint, nil
 }
 
-func getResumePoint(userID string) (*models.ResumePoint, error) {
+// getLatestResumePoint returns the user's most recently updated unfinished
+// content across all devices, or the next chapter to start.
+func getLatestResumePoint(ctx context.Context, userID string) (*models.ResumePoint, error) {
 	var resumePoint models.ResumePoint
 	var videoTimestamp float64
 	var quizIndex int
+	var updatedAt string
 
-	err := database.DB.QueryRow(`
+	err := database.DB.QueryRowContext(ctx, `
 		SELECT 
 			up.chapter_id, c.title, up.content_type, 
-			COALESCE(up.video_timestamp, 0), COALESCE(up.quiz_question_index, 0)
+			COALESCE(up.video_timestamp, 0), COALESCE(up.quiz_question_index, 0),
+			COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', up.updated_at), '')
 		FROM user_progress up
 		JOIN chapters c ON up.chapter_id = c.id
 		WHERE up.user_id = ? AND up.completed = 0
 		ORDER BY up.updated_at DESC
 		LIMIT 1
-	`, userID).Scan(&resumePoint.ChapterID, &resumePoint.ChapterTitle, &resumePoint.ContentType, &videoTimestamp, &quizIndex)
+	`, userID).Scan(&resumePoint.ChapterID, &resumePoint.ChapterTitle, &resumePoint.ContentType, &videoTimestamp, &quizIndex, &updatedAt)
 
 	if err != nil {
-		return getNextChapterToStart(userID)
+		return getNextChapterToStart(ctx, userID)
 	}
 
 	resumePoint.VideoTimestamp = videoTimestamp
 	resumePoint.QuizQuestionIndex = quizIndex
+	if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
+		resumePoint.UpdatedAt = &t
+	}
 
 	if resumePoint.ContentType == "quiz" {
-		database.DB.QueryRow(
+		err := database.DB.QueryRowContext(ctx,
 			"SELECT COUNT(*) FROM quiz_questions WHERE chapter_id = ?",
 			resumePoint.ChapterID,
 		).Scan(&resumePoint.TotalQuestions)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to count quiz questions",
+				"user_id", userID, "chapter_id", resumePoint.ChapterID, "error", err)
+		}
 	}
 
 	return &resumePoint, nil
 }
 
-func getNextChapterToStart(userID string) (*models.ResumePoint, error) {
+func getNextChapterToStart(ctx context.Context, userID string) (*models.ResumePoint, error) {
 	var resumePoint models.ResumePoint
 
-	err := database.DB.QueryRow(`
+	err := database.DB.QueryRowContext(ctx, `
 		SELECT c.id, c.title
-		FROM chapters c
-		LEFT JOIN user_progress vp ON c.id = vp.chapter_id AND vp.user_id = ? AND vp.content_type = 'video'
-		LEFT JOIN user_progress qp ON c.id = qp.chapter_id AND qp.user_id = ? AND qp.content_type = 'quiz'
+		FROM chapters c`+reporting.ChapterProgressJoins("?")+`
 		WHERE vp.completed = 1 AND qp.id IS NULL
 		ORDER BY c.order_index
 		LIMIT 1
@@ -210,14 +548,18 @@ func getNextChapterToStart(userID string) (*models.ResumePoint, error) {
 	if err == nil {
 		resumePoint.ContentType = "quiz"
 		resumePoint.QuizQuestionIndex = 0
-		database.DB.QueryRow(
+		err := database.DB.QueryRowContext(ctx,
 			"SELECT COUNT(*) FROM quiz_questions WHERE chapter_id = ?",
 			resumePoint.ChapterID,
 		).Scan(&resumePoint.TotalQuestions)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to count quiz questions",
+				"user_id", userID, "chapter_id", resumePoint.ChapterID, "error", err)
+		}
 		return &resumePoint, nil
 	}
 
-	err = database.DB.QueryRow(`
+	err = database.DB.QueryRowContext(ctx, `
 		SELECT c.id, c.title
 		FROM chapters c
 		LEFT JOIN user_progress up ON c.id = up.chapter_id AND up.user_id = ?
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/models"
+	"resume-learning-backend/reporting"
+
+	"github.com/gorilla/mux"
+)
+
+func GetCohortReport(w http.ResponseWriter, r *http.Request) {
+	reports, err := reporting.CohortFunnels(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to build cohort report", "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to build cohort report")
+		return
+	}
+
+	if mux.Vars(r)["format"] == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="cohorts.csv"`)
+		if err := reporting.WriteCohortCSV(w, reports); err != nil {
+			slog.ErrorContext(r.Context(), "Cohort report export aborted", "format", "csv", "error", err)
+		}
+		return
+	}
+
+	if reports == nil {
+		reports = []models.CohortReport{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"cohorts": reports})
+}
+
+const (
+	exportFlushRows    = 100
+	exportWriteTimeout = 30 * time.Second
+)
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+func ExportProgress(w http.ResponseWriter, r *http.Request) {
+	format := mux.Vars(r)["format"]
+
+	filter, err := parseProgressFilter(r)
+	if err != nil {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="progress.`+format+`"`)
+
+	// Like the progress stream, the export may outlast the server's write
+	// timeout, so the deadline is pushed back each time a batch is flushed.
+	rc := http.NewResponseController(w)
+	extendDeadline := func() error {
+		err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil
+		}
+		return err
+	}
+	if err := extendDeadline(); err != nil {
+		slog.ErrorContext(r.Context(), "Progress export aborted", "format", format, "error", err)
+		return
+	}
+
+	enc := reporting.NewProgressEncoder(format, w)
+	written := 0
+
+	err = reporting.StreamProgress(r.Context(), filter, func(row models.ProgressExportRow) error {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushRows == 0 {
+			if err := extendDeadline(); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Progress export aborted", "rows_written", written, "format", format, "error", err)
+		return
+	}
+
+	if err := enc.Close(); err != nil {
+		slog.ErrorContext(r.Context(), "Progress export aborted", "rows_written", written, "format", format, "error", err)
+	}
+}
+
+func parseProgressFilter(r *http.Request) (reporting.ProgressFilter, error) {
+	var filter reporting.ProgressFilter
+	query := r.URL.Query()
+
+	if v := query.Get("from"); v != "" {
+		t, _, err := parseDateParam(v)
+		if err != nil {
+			return filter, errors.New("Invalid from parameter")
+		}
+		filter.From = t
+	}
+
+	if v := query.Get("to"); v != "" {
+		t, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return filter, errors.New("Invalid to parameter")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = t
+	}
+
+	if v := query.Get("chapter_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, errors.New("Invalid chapter_id parameter")
+		}
+		filter.ChapterID = id
+	}
+
+	filter.UserID = query.Get("user_id")
+	return filter, nil
+}
+
+func parseDateParam(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"resume-learning-backend/apierror"
+)
+
+const maxBodyBytes = 64 << 10
+
+// decodeJSON decodes a single JSON object from the request body into dst,
+// rejecting bodies over maxBodyBytes, unknown fields and trailing data. On
+// failure it writes a problem response and returns false; type mismatches
+// and unknown fields are reported against the offending field.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	return decodeJSONLimit(w, r, dst, maxBodyBytes)
+}
+
+// decodeJSONLimit is decodeJSON with a caller-chosen body size limit, for
+// endpoints that take batches.
+func decodeJSONLimit(w http.ResponseWriter, r *http.Request, dst interface{}, limit int64) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		if dec.Decode(&struct{}{}) != io.EOF {
+			apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeInvalidBody, "Request body must contain a single JSON object")
+			return false
+		}
+		return true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		apierror.Respond(w, r, http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "Request body is too large")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		apierror.RespondValidation(w, r, []apierror.FieldError{
+			{Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()},
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		apierror.RespondValidation(w, r, []apierror.FieldError{{Field: field, Message: "is not allowed"}})
+	default:
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeInvalidBody, "Invalid request body")
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+)
+
+const (
+	defaultSearchResults = 20
+	maxSearchResults     = 50
+	maxSearchTerms       = 10
+
+	// snippet() wraps matches in these private-use characters rather than
+	// HTML, so the text around them can be escaped before the <mark> tags
+	// are put in.
+	snippetMatchStart = "\uE000"
+	snippetMatchEnd   = "\uE001"
+)
+
+var snippetMarkup = strings.NewReplacer(snippetMatchStart, "<mark>", snippetMatchEnd, "</mark>")
+
+// Search finds chapters, quiz questions and caption transcripts matching
+// ?q= using the SQLite FTS5 index. Words are matched after stemming, the
+// last word also as a prefix so results appear while typing, and title
+// matches rank above body matches.
+func Search(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "q is required")
+		return
+	}
+	if len(q) > maxSearchLength {
+		apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, fmt.Sprintf("q must be at most %d characters", maxSearchLength))
+		return
+	}
+
+	limit := defaultSearchResults
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchResults {
+			apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSearchResults))
+			return
+		}
+		limit = n
+	}
+
+	response := models.SearchResponse{Query: q, Results: []models.SearchResult{}}
+
+	match := ftsQuery(q)
+	if match != "" {
+		results, err := searchContent(r.Context(), match, limit)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Search failed", "query", q, "error", err)
+			apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Search failed")
+			return
+		}
+		response.Results = results
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ftsQuery turns free text into an FTS5 query that cannot fail to parse:
+// each word becomes a quoted phrase, so operators and punctuation typed by
+// the user are matched literally, and the last word gets a prefix star.
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"`
+	}
+	if len(terms) > 0 {
+		terms[len(terms)-1] += "*"
+	}
+
+	return strings.Join(terms, " ")
+}
+
+func searchContent(ctx context.Context, match string, limit int) ([]models.SearchResult, error) {
+	// bm25 takes one weight per column, unindexed ones included: kind,
+	// chapter_id, ref_id, title, body.
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT s.kind, s.chapter_id, s.ref_id, c.title,
+			COALESCE(cc.language, ''), COALESCE(cc.start_seconds, 0),
+			snippet(search_index, -1, ?, ?, '…', 12),
+			-bm25(search_index, 0, 0, 0, 10.0, 1.0) AS score
+		FROM search_index s
+		JOIN chapters c ON c.id = s.chapter_id
+		LEFT JOIN caption_cues cc ON s.kind = 'transcript' AND cc.id = s.ref_id
+		WHERE search_index MATCH ?
+		ORDER BY score DESC
+		LIMIT ?
+	`, snippetMatchStart, snippetMatchEnd, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var res models.SearchResult
+		var refID int
+		err := rows.Scan(&res.Type, &res.ChapterID, &refID, &res.ChapterTitle,
+			&res.Language, &res.VideoTimestamp, &res.Snippet, &res.Score)
+		if err != nil {
+			return nil, err
+		}
+		if res.Type == "question" {
+			res.QuestionID = refID
+		}
+		res.Snippet = highlightSnippet(res.Snippet)
+		results = append(results, res)
+	}
+
+	return results, rows.Err()
+}
+
+// highlightSnippet HTML-escapes an FTS snippet and turns its match markers
+// into <mark> tags. Indexed text is stored unescaped (caption cues are
+// entity-decoded), so escaping is what keeps it from becoming markup.
+func highlightSnippet(snippet string) string {
+	return snippetMarkup.Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/events"
+	"resume-learning-backend/metrics"
+	"resume-learning-backend/middleware"
+	"resume-learning-backend/models"
+)
+
+const (
+	streamHeartbeat    = 25 * time.Second
+	streamWriteTimeout = 10 * time.Second
+	streamRetry        = 5 * time.Second
+)
+
+var progressHub *events.Hub
+
+// SetProgressHub sets the hub that progress writes publish to and progress
+// streams subscribe to. Without one, writes publish nothing and streaming
+// is unavailable.
+func SetProgressHub(h *events.Hub) {
+	progressHub = h
+}
+
+func publishProgress(userID string, ev models.ProgressEvent) {
+	if progressHub != nil {
+		progressHub.Publish(userID, ev)
+	}
+}
+
+// StreamProgress pushes the user's progress changes as Server-Sent Events,
+// so every open session sees saves made on other devices. Each event is a
+// "progress" event whose data is a models.ProgressEvent; comment lines are
+// sent as heartbeats to keep proxies from closing an idle stream.
+func StreamProgress(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+	if progressHub == nil {
+		apierror.Respond(w, r, http.StatusServiceUnavailable, apierror.CodeInternal, "Progress streaming is not available")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+
+	// The server's write timeout is sized for ordinary requests; a stream
+	// instead gets a fresh deadline before every write.
+	write := func(format string, args ...interface{}) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	sub := progressHub.Subscribe(userID)
+	defer sub.Close()
+
+	metrics.StreamOpened()
+	defer metrics.StreamClosed()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := write("retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+		slog.WarnContext(r.Context(), "Failed to start progress stream", "user_id", userID, "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			err = write("event: progress\ndata: %s\n\n", data)
+		case <-heartbeat.C:
+			err = write(": ping\n\n")
+		}
+		if err != nil {
+			slog.DebugContext(r.Context(), "Progress stream closed", "user_id", userID, "error", err)
+			return
+		}
+	}
+}
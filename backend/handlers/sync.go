@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/database"
+	"resume-learning-backend/metrics"
+	"resume-learning-backend/middleware"
+	"resume-learning-backend/models"
+)
+
+const (
+	maxSyncBodyBytes = 1 << 20
+	maxSyncEvents    = 500
+)
+
+// SyncProgress merges a batch of progress events that a client queued while
+// offline and returns the user's merged progress. Video positions merge to
+// the furthest point reached; quiz answers merge per question, keeping the
+// answer recorded last.
+func SyncProgress(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r)
+	if userID == "" {
+		apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.SyncRequest
+	if !decodeJSONLimit(w, r, &req, maxSyncBodyBytes) {
+		return
+	}
+
+	errs, err := validateSyncRequest(r.Context(), req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to validate progress sync", "user_id", userID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to sync progress")
+		return
+	}
+	if len(errs) > 0 {
+		apierror.RespondValidation(w, r, errs)
+		return
+	}
+
+	flushPendingVideo(r.Context(), userID)
+
+	device := middleware.GetDevice(r)
+	merged, err := mergeSyncEvents(r.Context(), userID, device, req.Events)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to merge progress sync",
+			"user_id", userID, "events", len(req.Events), "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to sync progress")
+		return
+	}
+
+	progress, err := getUserProgress(r.Context(), userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to fetch progress", "user_id", userID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to fetch progress")
+		return
+	}
+
+	publishMergedProgress(userID, device, merged, progress)
+
+	resumePoint, err := getResumePoint(r.Context(), userID, device.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.ErrorContext(r.Context(), "Failed to compute resume point", "user_id", userID, "error", err)
+	}
+
+	json.NewEncoder(w).Encode(models.SyncResponse{
+		Received:    len(req.Events),
+		Applied:     len(merged),
+		Progress:    progress,
+		ResumePoint: resumePoint,
+	})
+}
+
+// publishMergedProgress pushes the merged state of each row a sync changed
+// to the user's open streams.
+func publishMergedProgress(userID string, device middleware.Device, merged []mergedProgress, progress []models.UserProgress) {
+	for _, m := range merged {
+		for _, p := range progress {
+			if p.ChapterID != m.chapterID || p.ContentType != m.contentType {
+				continue
+			}
+			publishProgress(userID, models.ProgressEvent{
+				ChapterID:         p.ChapterID,
+				ContentType:       p.ContentType,
+				VideoTimestamp:    p.VideoTimestamp,
+				QuizQuestionIndex: p.QuizQuestionIndex,
+				Completed:         p.Completed,
+				DeviceID:          device.ID,
+				UpdatedAt:         p.UpdatedAt,
+			})
+		}
+	}
+}
+
+func validateSyncRequest(ctx context.Context, req models.SyncRequest) (validationErrors, error) {
+	var errs validationErrors
+
+	switch {
+	case len(req.Events) == 0:
+		errs.add("events", "must contain at least one event")
+		return errs, nil
+	case len(req.Events) > maxSyncEvents:
+		errs.add("events", "must contain at most %d events", maxSyncEvents)
+		return errs, nil
+	}
+
+	shapes := make(map[int]chapterShape)
+	shapeFor := func(chapterID int) (chapterShape, error) {
+		if shape, ok := shapes[chapterID]; ok {
+			return shape, nil
+		}
+		shape, err := loadChapterShapeFor(ctx, chapterID)
+		if err == nil {
+			shapes[chapterID] = shape
+		}
+		return shape, err
+	}
+
+	for i, ev := range req.Events {
+		prefix := fmt.Sprintf("events[%d]", i)
+		if ev.RecordedAt.IsZero() {
+			errs.add(prefix+".recorded_at", "is required")
+		}
+
+		switch {
+		case (ev.Video == nil) == (ev.Quiz == nil):
+			errs.add(prefix, "must contain exactly one of video or quiz")
+		case ev.Video != nil:
+			shape, err := shapeFor(ev.Video.ChapterID)
+			if err != nil {
+				return nil, err
+			}
+			errs.addNested(prefix+".video", checkVideoProgress(*ev.Video, shape))
+		default:
+			shape, err := shapeFor(ev.Quiz.ChapterID)
+			if err != nil {
+				return nil, err
+			}
+			errs.addNested(prefix+".quiz", checkQuizProgress(*ev.Quiz, shape))
+		}
+	}
+
+	return errs, nil
+}
+
+// mergeSyncEvents applies events in recorded order inside one transaction
+// and returns the progress rows that changed. The device's own positions
+// are set to its last event per chapter. Client clocks ahead of the server
+// are clamped to now so a skewed device cannot pin its answers.
+func mergeSyncEvents(ctx context.Context, userID string, device middleware.Device, events []models.SyncEvent) ([]mergedProgress, error) {
+	sorted := make([]models.SyncEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].RecordedAt.Before(sorted[j].RecordedAt) })
+
+	now := time.Now()
+	videos := make(map[int][]models.SyncEvent)
+	quizzes := make(map[int][]models.SyncEvent)
+	var videoChapters, quizChapters []int
+	for i := range sorted {
+		ev := &sorted[i]
+		if ev.RecordedAt.After(now) {
+			ev.RecordedAt = now
+		}
+		if ev.Video != nil {
+			if _, ok := videos[ev.Video.ChapterID]; !ok {
+				videoChapters = append(videoChapters, ev.Video.ChapterID)
+			}
+			videos[ev.Video.ChapterID] = append(videos[ev.Video.ChapterID], *ev)
+		} else {
+			if _, ok := quizzes[ev.Quiz.ChapterID]; !ok {
+				quizChapters = append(quizChapters, ev.Quiz.ChapterID)
+			}
+			quizzes[ev.Quiz.ChapterID] = append(quizzes[ev.Quiz.ChapterID], *ev)
+		}
+	}
+
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var saved []mergedProgress
+	for _, chapterID := range videoChapters {
+		merged, err := mergeVideoEvents(ctx, tx, userID, chapterID, videos[chapterID])
+		if err != nil {
+			return nil, fmt.Errorf("chapter %d video: %w", chapterID, err)
+		}
+		if merged != nil {
+			saved = append(saved, *merged)
+		}
+	}
+	for _, chapterID := range quizChapters {
+		merged, err := mergeQuizEvents(ctx, tx, userID, chapterID, quizzes[chapterID])
+		if err != nil {
+			return nil, fmt.Errorf("chapter %d quiz: %w", chapterID, err)
+		}
+		if merged != nil {
+			saved = append(saved, *merged)
+		}
+	}
+
+	for _, chapterID := range videoChapters {
+		last := videos[chapterID][len(videos[chapterID])-1]
+		err := recordDeviceProgress(ctx, tx, userID, device, deviceProgress{
+			chapterID:      chapterID,
+			contentType:    "video",
+			videoTimestamp: last.Video.Timestamp,
+			completed:      last.Video.Completed,
+			at:             last.RecordedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("chapter %d video device progress: %w", chapterID, err)
+		}
+	}
+	for _, chapterID := range quizChapters {
+		last := quizzes[chapterID][len(quizzes[chapterID])-1]
+		err := recordDeviceProgress(ctx, tx, userID, device, deviceProgress{
+			chapterID:   chapterID,
+			contentType: "quiz",
+			quizIndex:   last.Quiz.QuestionIndex,
+			completed:   last.Quiz.Completed,
+			at:          last.RecordedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("chapter %d quiz device progress: %w", chapterID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range saved {
+		if m.contentType == "video" {
+			metrics.VideoSaved(m.chapterID, m.completed)
+		} else {
+			metrics.QuizSubmitted(m.chapterID, m.completed)
+		}
+	}
+
+	return saved, nil
+}
+
+// mergedProgress identifies a progress row changed by a sync.
+type mergedProgress struct {
+	chapterID   int
+	contentType string
+	completed   bool
+}
+
+// mergeVideoEvents folds a chapter's video events into the stored row. The
+// furthest position wins regardless of order, and completion is sticky. It
+// returns nil when the stored row already covers every event.
+func mergeVideoEvents(ctx context.Context, tx *database.InstrumentedTx, userID string, chapterID int, events []models.SyncEvent) (*mergedProgress, error) {
+	var stored struct {
+		position, duration, maxPosition float64
+		completed                       bool
+	}
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(video_timestamp, 0), COALESCE(video_duration, 0), COALESCE(video_max_timestamp, 0), completed
+		FROM user_progress
+		WHERE user_id = ? AND chapter_id = ? AND content_type = 'video'
+	`, userID, chapterID).Scan(&stored.position, &stored.duration, &stored.maxPosition, &stored.completed)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	position, duration, completed := stored.position, stored.duration, stored.completed
+	for _, ev := range events {
+		position = math.Max(position, ev.Video.Timestamp)
+		duration = math.Max(duration, ev.Video.Duration)
+		completed = completed || ev.Video.Completed
+	}
+
+	if exists && position == stored.position && duration == stored.duration && completed == stored.completed {
+		return nil, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, chapter_id, content_type, video_timestamp, video_duration, video_max_timestamp, completed, started_at, updated_at)
+		VALUES (?, ?, 'video', ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, chapter_id, content_type)
+		DO UPDATE SET video_timestamp = excluded.video_timestamp,
+			video_duration = excluded.video_duration,
+			video_max_timestamp = excluded.video_max_timestamp,
+			completed = excluded.completed,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, chapterID, position, duration, math.Max(stored.maxPosition, position), completed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mergedProgress{chapterID: chapterID, contentType: "video", completed: completed}, nil
+}
+
+// mergeQuizEvents folds a chapter's quiz events into the stored row. Each
+// question keeps the answer with the latest clock entry (see answerClock);
+// rows saved before clocks were tracked count as answered at updated_at.
+// The question index follows the most recent save, and completion is
+// sticky. It returns nil when no event changes the stored row.
+func mergeQuizEvents(ctx context.Context, tx *database.InstrumentedTx, userID string, chapterID int, events []models.SyncEvent) (*mergedProgress, error) {
+	var index int
+	var answersJSON, timesJSON, clockJSON string
+	var completed bool
+	var updatedAt int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(quiz_question_index, 0), COALESCE(quiz_answers, '[]'), COALESCE(quiz_answer_times, '[]'),
+			COALESCE(quiz_answer_clock, '[]'), completed, COALESCE(CAST(strftime('%s', updated_at) AS INTEGER), 0) * 1000
+		FROM user_progress
+		WHERE user_id = ? AND chapter_id = ? AND content_type = 'quiz'
+	`, userID, chapterID).Scan(&index, &answersJSON, &timesJSON, &clockJSON, &completed, &updatedAt)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var storedAnswers []int
+	var storedTimes []float64
+	var storedClock []int64
+	if exists {
+		if err := json.Unmarshal([]byte(answersJSON), &storedAnswers); err != nil {
+			slog.WarnContext(ctx, "Ignoring invalid stored quiz answers", "user_id", userID, "chapter_id", chapterID, "error", err)
+		}
+		json.Unmarshal([]byte(timesJSON), &storedTimes)
+		json.Unmarshal([]byte(clockJSON), &storedClock)
+	}
+
+	questions := len(events[0].Quiz.Answers)
+	answers := make([]int, questions)
+	times := make([]float64, questions)
+	clock := make([]int64, questions)
+	var lastActivity int64
+	for i := range answers {
+		answers[i] = -1
+		if i < len(storedAnswers) {
+			answers[i] = storedAnswers[i]
+		}
+		if i < len(storedTimes) {
+			times[i] = storedTimes[i]
+		}
+		switch {
+		case i < len(storedClock):
+			clock[i] = storedClock[i]
+		case answers[i] >= 0:
+			clock[i] = updatedAt
+		}
+		if clock[i] > lastActivity {
+			lastActivity = clock[i]
+		}
+	}
+
+	changed := !exists
+	for _, ev := range events {
+		at := ev.RecordedAt.UnixMilli()
+		quiz := ev.Quiz
+		for i, answer := range quiz.Answers {
+			if answer < 0 || at < clock[i] {
+				continue
+			}
+			if answers[i] != answer || clock[i] != at {
+				changed = true
+			}
+			answers[i], clock[i] = answer, at
+			if i < len(quiz.AnswerTimes) {
+				times[i] = quiz.AnswerTimes[i]
+			}
+		}
+		if at >= lastActivity {
+			if index != quiz.QuestionIndex {
+				changed = true
+			}
+			index, lastActivity = quiz.QuestionIndex, at
+		}
+		if quiz.Completed && !completed {
+			completed, changed = true, true
+		}
+	}
+
+	if !changed {
+		return nil, nil
+	}
+
+	mergedAnswers, _ := json.Marshal(answers)
+	mergedTimes, _ := json.Marshal(times)
+	mergedClock, _ := json.Marshal(clock)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, chapter_id, content_type, quiz_question_index, quiz_answers, quiz_answer_times, quiz_answer_clock, completed, started_at, updated_at)
+		VALUES (?, ?, 'quiz', ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, chapter_id, content_type)
+		DO UPDATE SET quiz_question_index = excluded.quiz_question_index,
+			quiz_answers = excluded.quiz_answers,
+			quiz_answer_times = excluded.quiz_answer_times,
+			quiz_answer_clock = excluded.quiz_answer_clock,
+			completed = excluded.completed,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, chapterID, index, string(mergedAnswers), string(mergedTimes), string(mergedClock), completed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mergedProgress{chapterID: chapterID, contentType: "quiz", completed: completed}, nil
+}
+
+// getUserProgress returns every progress row stored for the user, which is
+// the state an offline client replaces its local copy with after a sync.
+func getUserProgress(ctx context.Context, userID string) ([]models.UserProgress, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT id, user_id, chapter_id, content_type, COALESCE(video_timestamp, 0),
+			COALESCE(quiz_question_index, 0), COALESCE(quiz_answers, '[]'), completed,
+			COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', updated_at), '')
+		FROM user_progress
+		WHERE user_id = ?
+		ORDER BY chapter_id, content_type
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := []models.UserProgress{}
+	for rows.Next() {
+		var p models.UserProgress
+		var answersJSON, updatedAt string
+		err := rows.Scan(&p.ID, &p.UserID, &p.ChapterID, &p.ContentType, &p.VideoTimestamp,
+			&p.QuizQuestionIndex, &answersJSON, &p.Completed, &updatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(answersJSON), &p.QuizAnswers); err != nil || p.QuizAnswers == nil {
+			p.QuizAnswers = []int{}
+		}
+		p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		progress = append(progress, p)
+	}
+
+	return progress, rows.Err()
+}
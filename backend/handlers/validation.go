@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/catalog"
+	"resume-learning-backend/models"
+)
+
+const (
+	maxUserIDLength   = 128
+	maxNoteBodyLength = 10000
+)
+
+type validationErrors []apierror.FieldError
+
+func (v *validationErrors) add(field, format string, args ...interface{}) {
+	*v = append(*v, apierror.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// chapterShape describes the parts of a chapter that progress payloads are
+// checked against.
+type chapterShape struct {
+	exists      bool
+	optionCount []int
+}
+
+func loadChapterShape(ctx context.Context, chapterID int) (chapterShape, error) {
+	var shape chapterShape
+
+	cat, err := catalog.Get(ctx)
+	if err != nil {
+		return shape, err
+	}
+	if _, ok := cat.Chapter(chapterID); !ok {
+		return shape, nil
+	}
+	shape.exists = true
+
+	for _, q := range cat.Questions(chapterID) {
+		shape.optionCount = append(shape.optionCount, len(q.Options))
+	}
+
+	return shape, nil
+}
+
+func validateLogin(req models.LoginRequest) validationErrors {
+	var errs validationErrors
+	switch {
+	case req.UserID == "":
+		errs.add("user_id", "is required")
+	case len(req.UserID) > maxUserIDLength:
+		errs.add("user_id", "must be at most %d characters", maxUserIDLength)
+	}
+	return errs
+}
+
+func validateVideoProgress(ctx context.Context, req models.VideoProgressRequest) (validationErrors, error) {
+	shape, err := loadChapterShapeFor(ctx, req.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	return checkVideoProgress(req, shape), nil
+}
+
+func validateQuizProgress(ctx context.Context, req models.QuizProgressRequest) (validationErrors, error) {
+	shape, err := loadChapterShapeFor(ctx, req.ChapterID)
+	if err != nil {
+		return nil, err
+	}
+	return checkQuizProgress(req, shape), nil
+}
+
+// loadChapterShapeFor is loadChapterShape for a client-supplied ID, skipping
+// the lookup for IDs that cannot exist.
+func loadChapterShapeFor(ctx context.Context, chapterID int) (chapterShape, error) {
+	if chapterID <= 0 {
+		return chapterShape{}, nil
+	}
+	return loadChapterShape(ctx, chapterID)
+}
+
+func checkVideoProgress(req models.VideoProgressRequest, shape chapterShape) validationErrors {
+	var errs validationErrors
+
+	if req.Timestamp < 0 {
+		errs.add("timestamp", "must not be negative")
+	}
+	if req.Duration < 0 {
+		errs.add("duration", "must not be negative")
+	}
+	if req.Duration > 0 && req.Timestamp > req.Duration {
+		errs.add("timestamp", "must not exceed duration")
+	}
+	if req.ClientSequence < 0 {
+		errs.add("client_sequence", "must not be negative")
+	}
+
+	switch {
+	case req.ChapterID <= 0:
+		errs.add("chapter_id", "is required")
+	case !shape.exists:
+		errs.add("chapter_id", "chapter %d does not exist", req.ChapterID)
+	}
+
+	return errs
+}
+
+func checkQuizProgress(req models.QuizProgressRequest, shape chapterShape) validationErrors {
+	var errs validationErrors
+
+	if req.ClientSequence < 0 {
+		errs.add("client_sequence", "must not be negative")
+	}
+
+	if req.ChapterID <= 0 {
+		errs.add("chapter_id", "is required")
+		return errs
+	}
+	if !shape.exists {
+		errs.add("chapter_id", "chapter %d does not exist", req.ChapterID)
+		return errs
+	}
+
+	questions := len(shape.optionCount)
+	if req.QuestionIndex < 0 || req.QuestionIndex >= questions {
+		errs.add("question_index", "must be between 0 and %d", questions-1)
+	}
+
+	if len(req.Answers) != questions {
+		errs.add("answers", "must contain %d entries, one per question", questions)
+	} else {
+		for i, answer := range req.Answers {
+			if answer < -1 || answer >= shape.optionCount[i] {
+				errs.add(fmt.Sprintf("answers[%d]", i), "must be -1 or an option index below %d", shape.optionCount[i])
+			}
+		}
+	}
+
+	if len(req.AnswerTimes) != 0 && len(req.AnswerTimes) != questions {
+		errs.add("answer_times", "must be empty or contain %d entries", questions)
+	}
+	for i, t := range req.AnswerTimes {
+		if t < 0 {
+			errs.add(fmt.Sprintf("answer_times[%d]", i), "must not be negative")
+		}
+	}
+
+	return errs
+}
+
+func validateNote(req models.NoteRequest) validationErrors {
+	var errs validationErrors
+	if req.VideoTimestamp < 0 {
+		errs.add("video_timestamp", "must not be negative")
+	}
+	if utf8.RuneCountInString(req.Body) > maxNoteBodyLength {
+		errs.add("body", "must be at most %d characters", maxNoteBodyLength)
+	}
+	return errs
+}
+
+// addNested appends errors from a nested payload with their fields
+// prefixed, e.g. "events[2].video" + "timestamp".
+func (v *validationErrors) addNested(prefix string, nested validationErrors) {
+	for _, e := range nested {
+		*v = append(*v, apierror.FieldError{Field: prefix + "." + e.Field, Message: e.Message})
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/models"
+)
+
+func errorFields(errs validationErrors) []string {
+	fields := []string{}
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	return fields
+}
+
+func TestCheckVideoProgress(t *testing.T) {
+	chapter := chapterShape{exists: true}
+
+	tests := []struct {
+		name  string
+		req   models.VideoProgressRequest
+		shape chapterShape
+		want  []string
+	}{
+		{
+			name:  "valid",
+			req:   models.VideoProgressRequest{ChapterID: 1, Timestamp: 30, Duration: 120},
+			shape: chapter,
+			want:  []string{},
+		},
+		{
+			name:  "unknown duration",
+			req:   models.VideoProgressRequest{ChapterID: 1, Timestamp: 30},
+			shape: chapter,
+			want:  []string{},
+		},
+		{
+			name:  "negative timestamp",
+			req:   models.VideoProgressRequest{ChapterID: 1, Timestamp: -1, Duration: 120},
+			shape: chapter,
+			want:  []string{"timestamp"},
+		},
+		{
+			name:  "negative duration",
+			req:   models.VideoProgressRequest{ChapterID: 1, Duration: -5},
+			shape: chapter,
+			want:  []string{"duration"},
+		},
+		{
+			name:  "timestamp above duration",
+			req:   models.VideoProgressRequest{ChapterID: 1, Timestamp: 121, Duration: 120},
+			shape: chapter,
+			want:  []string{"timestamp"},
+		},
+		{
+			name:  "negative client sequence",
+			req:   models.VideoProgressRequest{ChapterID: 1, ClientSequence: -1},
+			shape: chapter,
+			want:  []string{"client_sequence"},
+		},
+		{
+			name: "missing chapter",
+			req:  models.VideoProgressRequest{Timestamp: 10},
+			want: []string{"chapter_id"},
+		},
+		{
+			name: "unknown chapter",
+			req:  models.VideoProgressRequest{ChapterID: 99, Timestamp: 10},
+			want: []string{"chapter_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorFields(checkVideoProgress(tt.req, tt.shape))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors on %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckQuizProgress(t *testing.T) {
+	// Three questions with 4, 4 and 2 options.
+	chapter := chapterShape{exists: true, optionCount: []int{4, 4, 2}}
+
+	tests := []struct {
+		name  string
+		req   models.QuizProgressRequest
+		shape chapterShape
+		want  []string
+	}{
+		{
+			name:  "valid",
+			req:   models.QuizProgressRequest{ChapterID: 1, QuestionIndex: 1, Answers: []int{2, -1, -1}},
+			shape: chapter,
+			want:  []string{},
+		},
+		{
+			name: "missing chapter",
+			req:  models.QuizProgressRequest{Answers: []int{0, 0, 0}},
+			want: []string{"chapter_id"},
+		},
+		{
+			name: "unknown chapter",
+			req:  models.QuizProgressRequest{ChapterID: 99, Answers: []int{0, 0, 0}},
+			want: []string{"chapter_id"},
+		},
+		{
+			name:  "question index too high",
+			req:   models.QuizProgressRequest{ChapterID: 1, QuestionIndex: 3, Answers: []int{0, 0, 0}},
+			shape: chapter,
+			want:  []string{"question_index"},
+		},
+		{
+			name:  "negative question index",
+			req:   models.QuizProgressRequest{ChapterID: 1, QuestionIndex: -1, Answers: []int{0, 0, 0}},
+			shape: chapter,
+			want:  []string{"question_index"},
+		},
+		{
+			name:  "too few answers",
+			req:   models.QuizProgressRequest{ChapterID: 1, Answers: []int{0, 0}},
+			shape: chapter,
+			want:  []string{"answers"},
+		},
+		{
+			name:  "too many answers",
+			req:   models.QuizProgressRequest{ChapterID: 1, Answers: []int{0, 0, 0, 0}},
+			shape: chapter,
+			want:  []string{"answers"},
+		},
+		{
+			name:  "option index too high",
+			req:   models.QuizProgressRequest{ChapterID: 1, Answers: []int{0, 4, 2}},
+			shape: chapter,
+			want:  []string{"answers[1]", "answers[2]"},
+		},
+		{
+			name:  "option index below -1",
+			req:   models.QuizProgressRequest{ChapterID: 1, Answers: []int{-2, 0, 0}},
+			shape: chapter,
+			want:  []string{"answers[0]"},
+		},
+		{
+			name:  "answer times wrong length",
+			req:   models.QuizProgressRequest{ChapterID: 1, Answers: []int{0, 0, 0}, AnswerTimes: []float64{1}},
+			shape: chapter,
+			want:  []string{"answer_times"},
+		},
+		{
+			name:  "negative answer time",
+			req:   models.QuizProgressRequest{ChapterID: 1, Answers: []int{0, 0, 0}, AnswerTimes: []float64{1, -1, 0}},
+			shape: chapter,
+			want:  []string{"answer_times[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorFields(checkQuizProgress(tt.req, tt.shape))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got errors on %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantOK     bool
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{
+			name:   "valid",
+			body:   `{"chapter_id": 1, "timestamp": 12.5}`,
+			wantOK: true,
+		},
+		{
+			name:       "unknown field",
+			body:       `{"chapter_id": 1, "position": 12.5}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   apierror.CodeValidation,
+			wantField:  "position",
+		},
+		{
+			name:       "wrong type",
+			body:       `{"chapter_id": "one"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   apierror.CodeValidation,
+			wantField:  "chapter_id",
+		},
+		{
+			name:       "trailing data",
+			body:       `{"chapter_id": 1} {"chapter_id": 2}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidBody,
+		},
+		{
+			name:       "malformed",
+			body:       `{"chapter_id": 1`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidBody,
+		},
+		{
+			name:       "oversized body",
+			body:       `{"chapter_id": 1, "timestamp": 1` + strings.Repeat(" ", maxBodyBytes) + `}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   apierror.CodeBodyTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/progress/video", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			var req models.VideoProgressRequest
+			ok := decodeJSON(w, r, &req)
+			if ok != tt.wantOK {
+				t.Fatalf("decodeJSON returned %v, want %v (response %d %s)", ok, tt.wantOK, w.Code, w.Body)
+			}
+			if tt.wantOK {
+				if req.ChapterID != 1 || req.Timestamp != 12.5 {
+					t.Errorf("decoded %+v", req)
+				}
+				return
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			var problem apierror.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("response is not a problem document: %v", err)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("code %q, want %q", problem.Code, tt.wantCode)
+			}
+			if tt.wantField != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.wantField) {
+				t.Errorf("errors %+v, want one on %q", problem.Errors, tt.wantField)
+			}
+		})
+	}
+}
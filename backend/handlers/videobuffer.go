@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/middleware"
+	"resume-learning-backend/models"
+	"resume-learning-backend/writebehind"
+)
+
+var videoBuffer *writebehind.Buffer
+
+// SetVideoBuffer makes SaveVideoProgress buffer in-progress heartbeats in b
+// instead of writing each one. Completed saves are always written at once.
+func SetVideoBuffer(b *writebehind.Buffer) {
+	videoBuffer = b
+}
+
+// FlushVideoWrites is the writebehind.FlushFunc for video progress: it
+// writes a batch of buffered saves and their device positions in one
+// transaction.
+func FlushVideoWrites(ctx context.Context, writes []writebehind.VideoWrite) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, w := range writes {
+		applied, err := saveVideoProgress(ctx, tx, w.UserID, w.DeviceID, w.Request, w.MaxTimestamp)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			continue
+		}
+
+		err = recordDeviceProgress(ctx, tx, w.UserID, middleware.Device{ID: w.DeviceID, Name: w.DeviceName}, deviceProgress{
+			chapterID:      w.Request.ChapterID,
+			contentType:    "video",
+			videoTimestamp: w.Request.Timestamp,
+			completed:      w.Request.Completed,
+			at:             w.At,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// saveVideoProgressNow writes a video save straight to the database. Any
+// buffered heartbeats for the user are flushed first so they cannot land
+// after it and roll it back.
+func saveVideoProgressNow(ctx context.Context, userID string, device middleware.Device, req models.VideoProgressRequest, at time.Time) (bool, error) {
+	if videoBuffer != nil {
+		if err := videoBuffer.FlushUser(ctx, userID); err != nil {
+			return false, err
+		}
+	}
+
+	applied, err := saveVideoProgress(ctx, database.DB, userID, device.ID, req, req.Timestamp)
+	if err != nil || !applied {
+		return applied, err
+	}
+
+	err = recordDeviceProgress(ctx, database.DB, userID, device, deviceProgress{
+		chapterID:      req.ChapterID,
+		contentType:    "video",
+		videoTimestamp: req.Timestamp,
+		completed:      req.Completed,
+		at:             at,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record device progress",
+			"user_id", userID, "chapter_id", req.ChapterID, "error", err)
+	}
+
+	return true, nil
+}
+
+// flushPendingVideo writes the user's buffered heartbeats before a read so
+// the read reflects them. A failed flush is logged and the read proceeds
+// with what is stored.
+func flushPendingVideo(ctx context.Context, userID string) {
+	if videoBuffer == nil {
+		return
+	}
+	if err := videoBuffer.FlushUser(ctx, userID); err != nil {
+		slog.ErrorContext(ctx, "Failed to flush buffered video progress", "user_id", userID, "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+	"resume-learning-backend/writebehind"
+)
+
+const (
+	benchViewers       = 200
+	benchFlushInterval = 100 * time.Millisecond
+)
+
+func openBenchDB(b *testing.B) {
+	b.Helper()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := database.InitDB("sqlite", filepath.Join(b.TempDir(), "bench.db")); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { database.CloseDB() })
+	if err := database.SeedData(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// heartbeat is the i-th save in a stream of heartbeats spread round-robin
+// over benchViewers users watching chapter 1.
+func heartbeat(i int) (string, models.VideoProgressRequest) {
+	userID := "viewer-" + strconv.Itoa(i%benchViewers)
+	return userID, models.VideoProgressRequest{
+		ChapterID: 1,
+		Timestamp: float64(i / benchViewers),
+		Duration:  600,
+	}
+}
+
+// BenchmarkVideoSaves compares writing every heartbeat straight to SQLite
+// with buffering them and writing the latest position per viewer once per
+// flush interval. saves/s is heartbeats accepted per second; rows/save is
+// how many database rows were written per heartbeat.
+func BenchmarkVideoSaves(b *testing.B) {
+	b.Run("direct", func(b *testing.B) {
+		openBenchDB(b)
+		ctx := context.Background()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			userID, req := heartbeat(i)
+			if _, err := saveVideoProgress(ctx, database.DB, userID, "", req, req.Timestamp); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StopTimer()
+
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "saves/s")
+		b.ReportMetric(1, "rows/save")
+	})
+
+	b.Run("buffered", func(b *testing.B) {
+		openBenchDB(b)
+		ctx := context.Background()
+
+		var rows atomic.Int64
+		buf := writebehind.New(benchFlushInterval, func(ctx context.Context, writes []writebehind.VideoWrite) error {
+			rows.Add(int64(len(writes)))
+			return FlushVideoWrites(ctx, writes)
+		})
+		buf.Start()
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			userID, req := heartbeat(i)
+			buf.Put(writebehind.VideoWrite{UserID: userID, Request: req, At: time.Now()})
+		}
+		if err := buf.Close(ctx); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "saves/s")
+		b.ReportMetric(float64(rows.Load())/float64(b.N), "rows/save")
+	})
+}
@@ -0,0 +1,207 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/database"
+	"resume-learning-backend/middleware"
+)
+
+const (
+	Header        = "Idempotency-Key"
+	ReplayHeader  = "Idempotent-Replayed"
+	maxKeyLength  = 255
+	maxBodyBytes  = 64 << 10
+	retention     = 24 * time.Hour
+	staleAfter    = time.Minute
+	purgeInterval = time.Hour
+)
+
+var (
+	purgeMu   sync.Mutex
+	lastPurge time.Time
+)
+
+// Middleware makes a write endpoint safe to retry. The first request with a
+// given Idempotency-Key runs normally and, if it succeeds, its response is
+// stored; later requests with the same key and body get that response
+// replayed without running the handler again. Requests without the header
+// pass straight through. Keys are scoped per user and kept for 24 hours.
+// A key whose first request never finished (the handler panicked or the
+// process died) is treated as free again after staleAfter.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			apierror.Respond(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		userID := middleware.GetUserID(r)
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			apierror.Respond(w, r, http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge, "Request body is too large")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := requestHash(r, body)
+		// Recording the outcome must not be cut short by the client going
+		// away, or the key would stay reserved.
+		ctx := context.WithoutCancel(r.Context())
+
+		reserved, err := reserve(ctx, userID, key, hash)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to reserve idempotency key", "user_id", userID, "error", err)
+			apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process request")
+			return
+		}
+
+		if !reserved {
+			replay(w, r, userID, key, hash)
+			return
+		}
+
+		finished := false
+		defer func() {
+			if finished {
+				return
+			}
+			if err := release(ctx, userID, key); err != nil {
+				slog.ErrorContext(ctx, "Failed to release idempotency key", "user_id", userID, "error", err)
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		finished = true
+
+		if rec.status >= 200 && rec.status < 300 {
+			err = complete(ctx, userID, key, rec.status, rec.body.Bytes())
+		} else {
+			err = release(ctx, userID, key)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to record idempotency key", "user_id", userID, "error", err)
+		}
+
+		purgeExpired(ctx)
+	})
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func reserve(ctx context.Context, userID, key, hash string) (bool, error) {
+	cutoff := time.Now().Add(-staleAfter).UTC().Format("2006-01-02 15:04:05")
+	_, err := database.DB.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND key = ? AND status_code = 0 AND created_at < ?",
+		userID, key, cutoff,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := database.DB.ExecContext(ctx,
+		"INSERT OR IGNORE INTO idempotency_keys (user_id, key, request_hash) VALUES (?, ?, ?)",
+		userID, key, hash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func replay(w http.ResponseWriter, r *http.Request, userID, key, hash string) {
+	var storedHash string
+	var status int
+	var body []byte
+	err := database.DB.QueryRowContext(r.Context(),
+		"SELECT request_hash, status_code, response_body FROM idempotency_keys WHERE user_id = ? AND key = ?",
+		userID, key,
+	).Scan(&storedHash, &status, &body)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierror.Respond(w, r, http.StatusConflict, apierror.CodeConflict, "Request with this Idempotency-Key was just released, retry")
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Failed to load idempotency key", "user_id", userID, "error", err)
+		apierror.Respond(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to process request")
+	case storedHash != hash:
+		apierror.Respond(w, r, http.StatusUnprocessableEntity, apierror.CodeIdempotencyMismatch,
+			"Idempotency-Key was already used with a different request")
+	case status == 0:
+		apierror.Respond(w, r, http.StatusConflict, apierror.CodeConflict, "A request with this Idempotency-Key is still in progress")
+	default:
+		w.Header().Set(ReplayHeader, "true")
+		w.WriteHeader(status)
+		w.Write(body)
+	}
+}
+
+func complete(ctx context.Context, userID, key string, status int, body []byte) error {
+	_, err := database.DB.ExecContext(ctx,
+		"UPDATE idempotency_keys SET status_code = ?, response_body = ? WHERE user_id = ? AND key = ?",
+		status, body, userID, key,
+	)
+	return err
+}
+
+func release(ctx context.Context, userID, key string) error {
+	_, err := database.DB.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?",
+		userID, key,
+	)
+	return err
+}
+
+func purgeExpired(ctx context.Context) {
+	purgeMu.Lock()
+	if time.Since(lastPurge) < purgeInterval {
+		purgeMu.Unlock()
+		return
+	}
+	lastPurge = time.Now()
+	purgeMu.Unlock()
+
+	cutoff := time.Now().Add(-retention).UTC().Format("2006-01-02 15:04:05")
+	if _, err := database.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE created_at < ?", cutoff); err != nil {
+		slog.ErrorContext(ctx, "Failed to purge idempotency keys", "error", err)
+	}
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -1,58 +1,157 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/cors"
 
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/catalog"
+	"resume-learning-backend/config"
 	"resume-learning-backend/database"
+	"resume-learning-backend/events"
 	"resume-learning-backend/handlers"
+	"resume-learning-backend/idempotency"
+	"resume-learning-backend/logging"
+	"resume-learning-backend/metrics"
 	"resume-learning-backend/middleware"
+	"resume-learning-backend/ratelimit"
+	"resume-learning-backend/server"
+	"resume-learning-backend/tracing"
+	"resume-learning-backend/writebehind"
 )
 
 func main() {
-	if err := database.InitDB("./learning.db"); err != nil {
-		log.Fatal("Failed to initialize database:", err)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
 	}
-	defer database.CloseDB()
 
-	if err := database.SeedData(); err != nil {
-		log.Println("Warning: Failed to seed data:", err)
+	logging.Setup(cfg.LogLevel)
+	slog.Info("Effective config", "config", cfg)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		slog.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
+	database.AddQueryHook(metrics.QueryHook)
+	database.AddQueryHook(tracing.QueryHook)
+	database.AddQueryHook(catalog.QueryHook)
+	if err := database.InitDB(cfg.DBDriver, cfg.DBPath); err != nil {
+		slog.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
+	}
+
+	if cfg.Seed {
+		if err := database.SeedData(); err != nil {
+			slog.Warn("Failed to seed data", "error", err)
+		}
 	}
 
 	r := mux.NewRouter()
+	r.Use(tracing.Middleware())
+	r.Use(middleware.RequestIDMiddleware)
+	r.Use(middleware.AccessLogMiddleware)
+	r.Use(metrics.Middleware)
+
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Respond(w, r, http.StatusNotFound, apierror.CodeNotFound, "Route not found")
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierror.Respond(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
+	})
+
+	probes := r.NewRoute().Subrouter()
+	probes.Use(middleware.JSONMiddleware)
+	probes.HandleFunc("/healthz", handlers.Healthz).Methods("GET")
+	probes.HandleFunc("/readyz", handlers.Readyz).Methods("GET")
+	probes.HandleFunc("/version", handlers.Version).Methods("GET")
+
 	api := r.PathPrefix("/api").Subrouter()
 
-	api.HandleFunc("/auth/login", handlers.Login).Methods("POST")
+	middleware.SetTrustedProxies(cfg.TrustedProxies)
+	limiter := ratelimit.NewMemoryStore()
+	limited := func(route string, limit ratelimit.Limit, h http.Handler) http.Handler {
+		return middleware.RateLimit(limiter, route, limit)(h)
+	}
+
+	api.Handle("/auth/login", limited("login", ratelimit.PerMinute(10, 5), http.HandlerFunc(handlers.Login))).Methods("POST")
 	api.HandleFunc("/auth/logout", handlers.Logout).Methods("POST")
 
+	hub := events.NewHub()
+	handlers.SetProgressHub(hub)
+
+	var videoBuffer *writebehind.Buffer
+	if interval := cfg.VideoFlushDuration(); interval > 0 {
+		videoBuffer = writebehind.New(interval, handlers.FlushVideoWrites)
+		videoBuffer.Start()
+		handlers.SetVideoBuffer(videoBuffer)
+	}
+
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(middleware.AuthMiddleware)
 	protected.Use(middleware.JSONMiddleware)
+	protected.Use(middleware.RateLimit(limiter, "api", ratelimit.PerSecond(20, 40)))
 
 	protected.HandleFunc("/chapters", handlers.GetChapters).Methods("GET")
 	protected.HandleFunc("/chapters/{id}", handlers.GetChapterDetail).Methods("GET")
 
+	protected.HandleFunc("/chapters/{id}/captions", handlers.ListCaptions).Methods("GET")
+	protected.HandleFunc("/chapters/{id}/captions/{lang}.vtt", handlers.GetCaptionFile).Methods("GET")
+	protected.HandleFunc("/chapters/{id}/transcript", handlers.GetTranscript).Methods("GET")
+	protected.HandleFunc("/chapters/{id}/notes", handlers.ListNotes).Methods("GET")
+	protected.HandleFunc("/chapters/{id}/notes", handlers.CreateNote).Methods("POST")
+	protected.HandleFunc("/notes/export.md", handlers.ExportNotes).Methods("GET")
+	protected.HandleFunc("/notes/{noteID:[0-9]+}", handlers.GetNote).Methods("GET")
+	protected.HandleFunc("/notes/{noteID:[0-9]+}", handlers.UpdateNote).Methods("PUT")
+	protected.HandleFunc("/notes/{noteID:[0-9]+}", handlers.DeleteNote).Methods("DELETE")
+	protected.HandleFunc("/search", handlers.Search).Methods("GET")
+
 	protected.HandleFunc("/progress", handlers.GetProgress).Methods("GET")
 	protected.HandleFunc("/progress/resume", handlers.GetResumePoint).Methods("GET")
-	protected.HandleFunc("/progress/video", handlers.SaveVideoProgress).Methods("POST")
-	protected.HandleFunc("/progress/quiz", handlers.SaveQuizProgress).Methods("POST")
-
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: true,
-	})
+	protected.Handle("/progress/video", limited("progress_video", ratelimit.PerSecond(1, 10), idempotency.Middleware(http.HandlerFunc(handlers.SaveVideoProgress)))).Methods("POST")
+	protected.Handle("/progress/quiz", limited("progress_quiz", ratelimit.PerSecond(2, 10), idempotency.Middleware(http.HandlerFunc(handlers.SaveQuizProgress)))).Methods("POST")
+	protected.HandleFunc("/progress/stream", handlers.StreamProgress).Methods("GET")
+	protected.Handle("/progress/sync", limited("progress_sync", ratelimit.PerMinute(10, 5), http.HandlerFunc(handlers.SyncProgress))).Methods("POST")
 
-	handler := c.Handler(r)
+	middleware.SetAdminUsers(cfg.AdminUserIDs)
+	middleware.SetAdminToken(cfg.AdminToken)
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminMiddleware)
+
+	admin.HandleFunc("/analytics/chapters/{id}/questions", handlers.GetQuizAnalytics).Methods("GET")
+	admin.HandleFunc("/analytics/chapters/{id}/video", handlers.GetVideoAnalytics).Methods("GET")
+	admin.HandleFunc("/reports/cohorts.{format:json|csv}", handlers.GetCohortReport).Methods("GET")
+	admin.HandleFunc("/reports/progress.{format:json|ndjson|csv}", handlers.ExportProgress).Methods("GET")
+	admin.HandleFunc("/chapters/{id}/captions/{lang}", handlers.UploadCaptions).Methods("PUT")
+	admin.HandleFunc("/chapters/{id}/captions/{lang}", handlers.DeleteCaptions).Methods("DELETE")
+
+	handler := middleware.CORS(cfg.CORSOrigins, cfg.CORSMethods, cfg.CORSHeaders, cfg.CORSAllowCredentials)(r)
+
+	srv := server.New(cfg, handler)
+	srv.OnDrain(hub.Close)
+	srv.OnShutdown(shutdownTracing)
+	srv.OnShutdown(func(ctx context.Context) error {
+		return database.CloseDB()
+	})
+	if videoBuffer != nil {
+		srv.OnShutdown(videoBuffer.Close)
+	}
 
-	port := ":8080"
-	log.Printf("Server starting on http://localhost%s", port)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := http.ListenAndServe(port, handler); err != nil {
-		log.Fatal("Server failed:", err)
+	if err := srv.Run(ctx); err != nil {
+		slog.Error("Server failed", "error", err)
+		os.Exit(1)
 	}
 }
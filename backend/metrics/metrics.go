@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"resume-learning-backend/middleware"
+)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database statement latency by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"query", "status"})
+
+	videoSaves = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "learning_video_saves_total",
+		Help: "Video progress saves by chapter.",
+	}, []string{"chapter_id"})
+
+	quizSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "learning_quiz_submissions_total",
+		Help: "Quiz progress submissions by chapter.",
+	}, []string{"chapter_id"})
+
+	completions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "learning_completions_total",
+		Help: "Video and quiz completions by chapter.",
+	}, []string{"chapter_id", "content_type"})
+
+	catalogLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "learning_catalog_cache_lookups_total",
+		Help: "Catalog cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	progressStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "learning_progress_streams",
+		Help: "Open progress event streams.",
+	})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records request latency labelled with the matched route
+// template rather than the raw path, so IDs in URLs don't explode the
+// number of series.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := middleware.NewResponseRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		httpRequestDuration.
+			WithLabelValues(route, r.Method, strconv.Itoa(rec.Status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+func QueryHook(ctx context.Context, query string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		dbQueryDuration.WithLabelValues(QueryName(query), status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// QueryName reduces a SQL statement to "<operation> <table>", e.g.
+// "select chapters", for use as a low-cardinality label.
+func QueryName(query string) string {
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) == 0 {
+		return "unknown"
+	}
+
+	op := fields[0]
+	for i, f := range fields[:len(fields)-1] {
+		switch {
+		case f == "from" && op == "select",
+			f == "into" && op == "insert",
+			f == "update" && i == 0,
+			f == "from" && op == "delete",
+			f == "table" && (op == "create" || op == "alter"):
+			table := fields[i+1]
+			if table == "if" && i+4 < len(fields) {
+				table = fields[i+4]
+			}
+			return op + " " + strings.Trim(table, "(`\"")
+		}
+	}
+
+	return op
+}
+
+func VideoSaved(chapterID int, completed bool) {
+	label := strconv.Itoa(chapterID)
+	videoSaves.WithLabelValues(label).Inc()
+	if completed {
+		completions.WithLabelValues(label, "video").Inc()
+	}
+}
+
+func QuizSubmitted(chapterID int, completed bool) {
+	label := strconv.Itoa(chapterID)
+	quizSubmissions.WithLabelValues(label).Inc()
+	if completed {
+		completions.WithLabelValues(label, "quiz").Inc()
+	}
+}
+
+func StreamOpened() {
+	progressStreams.Inc()
+}
+
+func StreamClosed() {
+	progressStreams.Dec()
+}
+
+func CatalogLookup(hit bool) {
+	if hit {
+		catalogLookups.WithLabelValues("hit").Inc()
+	} else {
+		catalogLookups.WithLabelValues("miss").Inc()
+	}
+}
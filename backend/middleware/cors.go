@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rs/cors"
+)
+
+// CORS answers preflight requests and sets CORS headers for the configured
+// origins. Origins may use a single "*" wildcard as validated by the config
+// package.
+func CORS(origins, methods, headers []string, allowCredentials bool) func(http.Handler) http.Handler {
+	return cors.New(cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   headers,
+		ExposedHeaders:   []string{RequestIDHeader, "Retry-After", "Idempotent-Replayed", "ETag"},
+		AllowCredentials: allowCredentials,
+		MaxAge:           600,
+	}).Handler
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"resume-learning-backend/config"
+)
+
+// preflight sends the OPTIONS request a browser makes before a
+// cross-origin POST with JSON and a bearer token, as the Flutter web app
+// does when served by `flutter run -d chrome` on a random localhost port.
+func preflight(origin string) (w *httptest.ResponseRecorder, reachedHandler bool) {
+	cfg := config.Default()
+	reached := false
+	h := CORS(cfg.CORSOrigins, cfg.CORSMethods, cfg.CORSHeaders, cfg.CORSAllowCredentials)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }),
+	)
+
+	r := httptest.NewRequest(http.MethodOptions, "/api/progress/video", nil)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "authorization,content-type,idempotency-key")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w, reached
+}
+
+func TestCORSPreflightFlutterWebOrigin(t *testing.T) {
+	for _, origin := range []string{"http://localhost:53124", "http://127.0.0.1:8081"} {
+		t.Run(origin, func(t *testing.T) {
+			w, reached := preflight(origin)
+
+			if w.Code != http.StatusNoContent && w.Code != http.StatusOK {
+				t.Errorf("status %d, want 2xx", w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, origin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != http.MethodPost {
+				t.Errorf("Access-Control-Allow-Methods = %q, want POST", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got == "" {
+				t.Error("Access-Control-Allow-Headers not set")
+			}
+			if reached {
+				t.Error("preflight was passed on to the handler")
+			}
+		})
+	}
+}
+
+func TestCORSPreflightForeignOrigin(t *testing.T) {
+	for _, origin := range []string{"https://evil.example.com", "http://localhost.evil.com:8080", "https://localhost"} {
+		t.Run(origin, func(t *testing.T) {
+			w, _ := preflight(origin)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+				t.Errorf("Access-Control-Allow-Methods = %q, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	DeviceIDHeader   = "X-Device-ID"
+	DeviceNameHeader = "X-Device-Name"
+
+	maxDeviceHeaderLength = 128
+)
+
+// Device identifies the client installation a request came from. Clients
+// generate the ID once per install; the name is a human label such as
+// "iPhone" or "Chrome on Mac" shown when offering to resume elsewhere.
+type Device struct {
+	ID   string
+	Name string
+}
+
+// GetDevice reads the device headers. A missing or oversized ID yields the
+// zero Device, and requests without one are not tracked per device.
+func GetDevice(r *http.Request) Device {
+	id := strings.TrimSpace(r.Header.Get(DeviceIDHeader))
+	if id == "" || len(id) > maxDeviceHeaderLength {
+		return Device{}
+	}
+
+	name := strings.TrimSpace(r.Header.Get(DeviceNameHeader))
+	if len(name) > maxDeviceHeaderLength {
+		name = name[:maxDeviceHeaderLength]
+	}
+
+	return Device{ID: id, Name: name}
+}
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
+
+	"resume-learning-backend/apierror"
 )
 
 type contextKey string
@@ -19,19 +22,19 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, `{"error": "Authorization header required"}`, http.StatusUnauthorized)
+			apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Authorization header required")
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, `{"error": "Invalid authorization format"}`, http.StatusUnauthorized)
+			apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid authorization format")
 			return
 		}
 
 		userID := parts[1]
 		if userID == "" {
-			http.Error(w, `{"error": "User ID required"}`, http.StatusUnauthorized)
+			apierror.Respond(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "User ID required")
 			return
 		}
 
@@ -40,6 +43,40 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+var adminUsers = map[string]bool{}
+
+func SetAdminUsers(ids []string) {
+	adminUsers = map[string]bool{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			adminUsers[id] = true
+		}
+	}
+}
+
+var adminToken []byte
+
+// SetAdminToken sets the secret admins must send in the X-Admin-Token
+// header. With no token set, every admin request is refused.
+func SetAdminToken(token string) {
+	adminToken = []byte(token)
+}
+
+// AdminMiddleware admits requests from an admin user ID that also carry the
+// admin token. The user ID alone is not enough: it is the bearer value and
+// anyone can send it.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := []byte(r.Header.Get("X-Admin-Token"))
+		if len(adminToken) == 0 || subtle.ConstantTimeCompare(token, adminToken) != 1 || !adminUsers[GetUserID(r)] {
+			apierror.Respond(w, r, http.StatusForbidden, apierror.CodeForbidden, "Admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func GetUserID(r *http.Request) string {
 	userID, ok := r.Context().Value(UserIDKey).(string)
 	if !ok {
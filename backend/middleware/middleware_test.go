@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		adminToken string
+		userID     string
+		header     string
+		want       int
+	}{
+		{name: "admin with token", adminToken: token, userID: "admin", header: token, want: http.StatusOK},
+		{name: "admin without token", adminToken: token, userID: "admin", want: http.StatusForbidden},
+		{name: "admin with wrong token", adminToken: token, userID: "admin", header: "0123456789abcdeX", want: http.StatusForbidden},
+		{name: "admin with token prefix", adminToken: token, userID: "admin", header: token[:8], want: http.StatusForbidden},
+		{name: "non-admin with token", adminToken: token, userID: "learner", header: token, want: http.StatusForbidden},
+		{name: "no token configured", userID: "admin", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAdminUsers([]string{"admin"})
+			SetAdminToken(tt.adminToken)
+			t.Cleanup(func() {
+				SetAdminUsers(nil)
+				SetAdminToken("")
+			})
+
+			h := AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/reports/cohorts.json", nil)
+			r = r.WithContext(context.WithValue(r.Context(), UserIDKey, tt.userID))
+			if tt.header != "" {
+				r.Header.Set("X-Admin-Token", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"resume-learning-backend/apierror"
+	"resume-learning-backend/ratelimit"
+)
+
+// sharedIPFactor scales a route's limit for the per-IP bucket applied to
+// authenticated requests, leaving room for several learners behind one NAT.
+const sharedIPFactor = 5
+
+type rateCheck struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// RateLimit limits requests to a route. Every request draws from a bucket
+// for its client IP; authenticated requests also draw from one for their
+// user ID, and their IP bucket is sharedIPFactor times larger. User IDs are
+// chosen by the client, so the IP bucket is what stops a caller from
+// escaping the limit by sending a new ID each time. If the store fails the
+// request is let through.
+func RateLimit(store ratelimit.Store, route string, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	sharedLimit := ratelimit.Limit{Rate: limit.Rate * sharedIPFactor, Burst: limit.Burst * sharedIPFactor}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+			checks := []rateCheck{{route + ":ip:" + ip, limit}}
+			if userID := GetUserID(r); userID != "" {
+				checks = []rateCheck{
+					{route + ":user:" + userID, limit},
+					{route + ":ip:" + ip, sharedLimit},
+				}
+			}
+
+			for _, c := range checks {
+				allowed, retryAfter, err := store.Allow(r.Context(), c.key, c.limit)
+				if err != nil {
+					slog.ErrorContext(r.Context(), "Rate limit check failed", "route", route, "error", err)
+					break
+				}
+				if !allowed {
+					seconds := int(math.Ceil(retryAfter.Seconds()))
+					if seconds < 1 {
+						seconds = 1
+					}
+					w.Header().Set("Retry-After", strconv.Itoa(seconds))
+					apierror.Respond(w, r, http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests, retry later")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the reverse proxies whose X-Forwarded-For and
+// X-Real-IP headers are believed. Each entry is an IP address or a CIDR
+// range; entries that do not parse are skipped, as config validation has
+// already rejected them.
+func SetTrustedProxies(proxies []string) {
+	trustedProxies = nil
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		if _, ipNet, err := net.ParseCIDR(p); err == nil {
+			trustedProxies = append(trustedProxies, ipNet)
+		}
+	}
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address rate limits are keyed on. Forwarding headers
+// are only used when the connection comes from a trusted proxy, since any
+// client can set them. X-Forwarded-For is read from the right, skipping
+// trusted proxies, so a client cannot pick its own address by prepending
+// entries; X-Real-IP is the fallback.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	remote := net.ParseIP(host)
+	if remote == nil || !isTrustedProxy(remote) {
+		return host
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if !isTrustedProxy(ip) {
+				return ip.String()
+			}
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return host
+}
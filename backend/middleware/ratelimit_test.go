@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"resume-learning-backend/ratelimit"
+)
+
+func rateLimitedRequest(h http.Handler, remoteAddr, userID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/chapters", nil)
+	r.RemoteAddr = remoteAddr
+	if userID != "" {
+		r = r.WithContext(context.WithValue(r.Context(), UserIDKey, userID))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitRotatingUserIDsShareIPBucket(t *testing.T) {
+	limit := ratelimit.PerMinute(1, 2)
+	h := RateLimit(ratelimit.NewMemoryStore(), "api", limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		if rateLimitedRequest(h, "203.0.113.7:5000", "flooder-"+strconv.Itoa(i)).Code == http.StatusOK {
+			allowed++
+		}
+	}
+	if want := limit.Burst * sharedIPFactor; allowed != want {
+		t.Errorf("allowed %d requests with a fresh user ID each, want %d", allowed, want)
+	}
+
+	if code := rateLimitedRequest(h, "198.51.100.1:5000", "flooder-0").Code; code != http.StatusOK {
+		t.Errorf("request from another IP got %d, want 200", code)
+	}
+}
+
+func TestRateLimitPerUser(t *testing.T) {
+	limit := ratelimit.PerMinute(1, 2)
+	h := RateLimit(ratelimit.NewMemoryStore(), "api", limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < limit.Burst; i++ {
+		if code := rateLimitedRequest(h, "203.0.113.7:5000", "u1").Code; code != http.StatusOK {
+			t.Fatalf("request %d got %d, want 200", i+1, code)
+		}
+	}
+
+	w := rateLimitedRequest(h, "203.0.113.7:5000", "u1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the user limit got %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+
+	if code := rateLimitedRequest(h, "203.0.113.7:5000", "u2").Code; code != http.StatusOK {
+		t.Errorf("another user on the same IP got %d, want 200", code)
+	}
+}
+
+func TestRateLimitAnonymousByIP(t *testing.T) {
+	limit := ratelimit.PerMinute(1, 2)
+	h := RateLimit(ratelimit.NewMemoryStore(), "login", limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < limit.Burst; i++ {
+		rateLimitedRequest(h, "203.0.113.7:5000", "")
+	}
+	if code := rateLimitedRequest(h, "203.0.113.7:6000", "").Code; code != http.StatusTooManyRequests {
+		t.Errorf("anonymous request over the limit got %d, want 429", code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		realIP     string
+		want       string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer ignores X-Forwarded-For", remoteAddr: "203.0.113.7:5000", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "untrusted peer ignores X-Real-IP", remoteAddr: "203.0.113.7:5000", realIP: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:5000", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "trusted proxy chain", remoteAddr: "10.0.0.2:5000", xff: "198.51.100.1, 10.0.0.9", want: "198.51.100.1"},
+		{name: "spoofed leftmost entry", remoteAddr: "10.0.0.2:5000", xff: "192.0.2.66, 198.51.100.1", want: "198.51.100.1"},
+		{name: "trusted proxy with X-Real-IP", remoteAddr: "10.0.0.2:5000", realIP: "198.51.100.1", want: "198.51.100.1"},
+		{name: "trusted proxy with garbage header", remoteAddr: "10.0.0.2:5000", xff: "not-an-ip", want: "10.0.0.2"},
+		{name: "trusted proxy without headers", remoteAddr: "10.0.0.2:5000", want: "10.0.0.2"},
+		{name: "single trusted address", remoteAddr: "[2001:db8::1]:5000", xff: "198.51.100.1", want: "198.51.100.1"},
+	}
+
+	SetTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/chapters", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitBehindTrustedProxy(t *testing.T) {
+	SetTrustedProxies([]string{"10.0.0.2"})
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	limit := ratelimit.PerMinute(1, 2)
+	h := RateLimit(ratelimit.NewMemoryStore(), "login", limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(remoteAddr, forwardedFor string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < limit.Burst; i++ {
+		request("10.0.0.2:5000", "198.51.100.1")
+	}
+	if code := request("10.0.0.2:5000", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("client over its limit got %d, want 429", code)
+	}
+	if code := request("10.0.0.2:5000", "198.51.100.2"); code != http.StatusOK {
+		t.Errorf("another client behind the same proxy got %d, want 200", code)
+	}
+
+	// An untrusted peer cannot dodge its own limit with a forged header.
+	for i := 0; i < limit.Burst; i++ {
+		request("203.0.113.7:5000", "198.51.100.3")
+	}
+	if code := request("203.0.113.7:5000", "198.51.100.4"); code != http.StatusTooManyRequests {
+		t.Errorf("untrusted peer with a forged X-Forwarded-For got %d, want 429", code)
+	}
+}
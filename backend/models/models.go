@@ -44,13 +44,20 @@ type UserProgress struct {
 	UpdatedAt         time.Time `json:"updated_at"`
 }
 
+// ResumePoint is where the learner should pick up. When it comes from a
+// specific device, DeviceID and DeviceName say which; Alternatives lists
+// the latest positions on the user's other devices, most recent first.
 type ResumePoint struct {
-	ChapterID         int     `json:"chapter_id"`
-	ChapterTitle      string  `json:"chapter_title"`
-	ContentType       string  `json:"content_type"`
-	VideoTimestamp    float64 `json:"video_timestamp,omitempty"`
-	QuizQuestionIndex int     `json:"quiz_question_index,omitempty"`
-	TotalQuestions    int     `json:"total_questions,omitempty"`
+	ChapterID         int           `json:"chapter_id"`
+	ChapterTitle      string        `json:"chapter_title"`
+	ContentType       string        `json:"content_type"`
+	VideoTimestamp    float64       `json:"video_timestamp,omitempty"`
+	QuizQuestionIndex int           `json:"quiz_question_index,omitempty"`
+	TotalQuestions    int           `json:"total_questions,omitempty"`
+	DeviceID          string        `json:"device_id,omitempty"`
+	DeviceName        string        `json:"device_name,omitempty"`
+	UpdatedAt         *time.Time    `json:"updated_at,omitempty"`
+	Alternatives      []ResumePoint `json:"alternatives,omitempty"`
 }
 
 type LoginRequest struct {
@@ -64,17 +71,52 @@ type LoginResponse struct {
 }
 
 type VideoProgressRequest struct {
-	ChapterID int     `json:"chapter_id"`
-	Timestamp float64 `json:"timestamp"`
-	Duration  float64 `json:"duration"`
-	Completed bool    `json:"completed"`
+	ChapterID      int     `json:"chapter_id"`
+	Timestamp      float64 `json:"timestamp"`
+	Duration       float64 `json:"duration"`
+	Completed      bool    `json:"completed"`
+	ClientSequence int64   `json:"client_sequence,omitempty"`
 }
 
 type QuizProgressRequest struct {
-	ChapterID     int   `json:"chapter_id"`
-	QuestionIndex int   `json:"question_index"`
-	Answers       []int `json:"answers"`
-	Completed     bool  `json:"completed"`
+	ChapterID      int       `json:"chapter_id"`
+	QuestionIndex  int       `json:"question_index"`
+	Answers        []int     `json:"answers"`
+	AnswerTimes    []float64 `json:"answer_times,omitempty"`
+	Completed      bool      `json:"completed"`
+	ClientSequence int64     `json:"client_sequence,omitempty"`
+}
+
+// ProgressEvent is pushed to a user's open progress streams whenever one of
+// their progress rows changes.
+type ProgressEvent struct {
+	ChapterID         int       `json:"chapter_id"`
+	ContentType       string    `json:"content_type"`
+	VideoTimestamp    float64   `json:"video_timestamp,omitempty"`
+	QuizQuestionIndex int       `json:"quiz_question_index,omitempty"`
+	Completed         bool      `json:"completed"`
+	DeviceID          string    `json:"device_id,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// SyncEvent is a progress save queued on the client while offline. Exactly
+// one of Video and Quiz is set; RecordedAt is the client's clock when the
+// event happened.
+type SyncEvent struct {
+	RecordedAt time.Time             `json:"recorded_at"`
+	Video      *VideoProgressRequest `json:"video,omitempty"`
+	Quiz       *QuizProgressRequest  `json:"quiz,omitempty"`
+}
+
+type SyncRequest struct {
+	Events []SyncEvent `json:"events"`
+}
+
+type SyncResponse struct {
+	Received    int            `json:"received"`
+	Applied     int            `json:"applied"`
+	Progress    []UserProgress `json:"progress"`
+	ResumePoint *ResumePoint   `json:"resume_point"`
 }
 
 type ChapterDetailResponse struct {
@@ -82,7 +124,156 @@ type ChapterDetailResponse struct {
 	Questions []QuizQuestion `json:"questions"`
 }
 
+type ChapterListResponse struct {
+	Chapters   []Chapter `json:"chapters"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
 type ProgressResponse struct {
 	Chapters    []ChapterWithProgress `json:"chapters"`
+	NextCursor  string                `json:"next_cursor,omitempty"`
 	ResumePoint *ResumePoint          `json:"resume_point,omitempty"`
 }
+
+// SearchResult is one match from /api/search. Type is "chapter",
+// "question" or "transcript"; transcript matches carry the caption language
+// and the video position where the cue starts. Snippet is the matching text
+// with matched terms wrapped in <mark> tags. Higher Score is a better match.
+type SearchResult struct {
+	Type           string  `json:"type"`
+	ChapterID      int     `json:"chapter_id"`
+	ChapterTitle   string  `json:"chapter_title"`
+	QuestionID     int     `json:"question_id,omitempty"`
+	Language       string  `json:"language,omitempty"`
+	VideoTimestamp float64 `json:"video_timestamp,omitempty"`
+	Snippet        string  `json:"snippet"`
+	Score          float64 `json:"score"`
+}
+
+type CaptionTrack struct {
+	Language  string    `json:"language"`
+	Label     string    `json:"label"`
+	URL       string    `json:"url"`
+	Cues      int       `json:"cues"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TranscriptCue struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+type TranscriptResponse struct {
+	ChapterID int             `json:"chapter_id"`
+	Language  string          `json:"language"`
+	Cues      []TranscriptCue `json:"cues"`
+}
+
+// Note is a learner's note at a moment in a chapter's video. A note with an
+// empty body is a bookmark.
+type Note struct {
+	ID             int       `json:"id"`
+	ChapterID      int       `json:"chapter_id"`
+	VideoTimestamp float64   `json:"video_timestamp"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type NoteRequest struct {
+	VideoTimestamp float64 `json:"video_timestamp"`
+	Body           string  `json:"body"`
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+type QuestionAnalytics struct {
+	QuestionID          int       `json:"question_id"`
+	OrderIndex          int       `json:"order_index"`
+	QuestionText        string    `json:"question_text"`
+	CorrectOption       int       `json:"correct_option"`
+	Responses           int       `json:"responses"`
+	CorrectRate         float64   `json:"correct_rate"`
+	OptionCounts        []int     `json:"option_counts"`
+	OptionRates         []float64 `json:"option_rates"`
+	DiscriminationIndex float64   `json:"discrimination_index"`
+	AvgTimeToAnswer     float64   `json:"avg_time_to_answer"`
+}
+
+type QuizAnalyticsResponse struct {
+	ChapterID    int                 `json:"chapter_id"`
+	ChapterTitle string              `json:"chapter_title"`
+	Learners     int                 `json:"learners"`
+	Questions    []QuestionAnalytics `json:"questions"`
+}
+
+type DropOffPoint struct {
+	Start     float64 `json:"start"`
+	Learners  int     `json:"learners"`
+	Retention float64 `json:"retention"`
+}
+
+type CompletionFunnel struct {
+	VideoStarted   int `json:"video_started"`
+	VideoCompleted int `json:"video_completed"`
+	QuizStarted    int `json:"quiz_started"`
+	QuizCompleted  int `json:"quiz_completed"`
+}
+
+type VideoAnalyticsResponse struct {
+	ChapterID           int              `json:"chapter_id"`
+	ChapterTitle        string           `json:"chapter_title"`
+	Learners            int              `json:"learners"`
+	Duration            float64          `json:"duration"`
+	BucketSize          float64          `json:"bucket_size"`
+	DropOff             []DropOffPoint   `json:"drop_off"`
+	MedianWatchPosition float64          `json:"median_watch_position"`
+	Funnel              CompletionFunnel `json:"funnel"`
+}
+
+type CohortChapterStats struct {
+	ChapterID              int     `json:"chapter_id"`
+	ChapterTitle           string  `json:"chapter_title"`
+	NotStarted             int     `json:"not_started"`
+	InProgress             int     `json:"in_progress"`
+	VideoDone              int     `json:"video_done"`
+	QuizDone               int     `json:"quiz_done"`
+	AverageScore           float64 `json:"average_score"`
+	AverageCompletionHours float64 `json:"average_completion_hours"`
+}
+
+type CohortReport struct {
+	Cohort   string               `json:"cohort"`
+	Learners int                  `json:"learners"`
+	Chapters []CohortChapterStats `json:"chapters"`
+}
+
+type ProgressExportRow struct {
+	UserID            string  `json:"user_id"`
+	ChapterID         int     `json:"chapter_id"`
+	ChapterTitle      string  `json:"chapter_title"`
+	VideoTimestamp    float64 `json:"video_timestamp"`
+	VideoDuration     float64 `json:"video_duration"`
+	VideoCompleted    bool    `json:"video_completed"`
+	VideoUpdatedAt    string  `json:"video_updated_at,omitempty"`
+	QuizQuestionIndex int     `json:"quiz_question_index"`
+	QuizCompleted     bool    `json:"quiz_completed"`
+	QuizScore         float64 `json:"quiz_score"`
+	QuizUpdatedAt     string  `json:"quiz_updated_at,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type VersionResponse struct {
+	Commit        string `json:"commit"`
+	BuildTime     string `json:"build_time"`
+	GoVersion     string `json:"go_version"`
+	SchemaVersion int    `json:"schema_version"`
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestStore(start time.Time) (*MemoryStore, *time.Time) {
+	now := start
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestMemoryStoreAllow(t *testing.T) {
+	ctx := context.Background()
+	limit := PerSecond(2, 3)
+	s, now := newTestStore(time.Unix(1_700_000_000, 0))
+
+	for i := 0; i < 3; i++ {
+		if ok, _, _ := s.Allow(ctx, "k", limit); !ok {
+			t.Fatalf("request %d within burst was rejected", i+1)
+		}
+	}
+
+	ok, retryAfter, err := s.Allow(ctx, "k", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("request over burst was allowed")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("retryAfter = %v, want 500ms", retryAfter)
+	}
+
+	*now = now.Add(250 * time.Millisecond)
+	if ok, retryAfter, _ := s.Allow(ctx, "k", limit); ok || retryAfter != 250*time.Millisecond {
+		t.Errorf("after 250ms got allowed=%v retryAfter=%v, want false 250ms", ok, retryAfter)
+	}
+
+	*now = now.Add(250 * time.Millisecond)
+	if ok, _, _ := s.Allow(ctx, "k", limit); !ok {
+		t.Error("request after refill was rejected")
+	}
+	if ok, _, _ := s.Allow(ctx, "k", limit); ok {
+		t.Error("refill granted more than one token")
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	limit := PerMinute(1, 1)
+	s, _ := newTestStore(time.Unix(1_700_000_000, 0))
+
+	if ok, _, _ := s.Allow(ctx, "a", limit); !ok {
+		t.Fatal("first request for a was rejected")
+	}
+	if ok, _, _ := s.Allow(ctx, "a", limit); ok {
+		t.Fatal("second request for a was allowed")
+	}
+	if ok, _, _ := s.Allow(ctx, "b", limit); !ok {
+		t.Error("exhausting a also limited b")
+	}
+}
+
+func TestMemoryStoreRefillCapsAtBurst(t *testing.T) {
+	ctx := context.Background()
+	limit := PerSecond(10, 2)
+	s, now := newTestStore(time.Unix(1_700_000_000, 0))
+
+	s.Allow(ctx, "k", limit)
+	*now = now.Add(time.Hour)
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if ok, _, _ := s.Allow(ctx, "k", limit); ok {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d requests after a long idle, want burst of 2", allowed)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	ctx := context.Background()
+	limit := PerSecond(1, 1)
+	s, now := newTestStore(time.Unix(1_700_000_000, 0))
+
+	s.Allow(ctx, "idle", limit)
+	*now = now.Add(2 * sweepInterval)
+	s.Allow(ctx, "active", limit)
+
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("refilled bucket was not swept")
+	}
+	if _, ok := s.buckets["active"]; !ok {
+		t.Error("bucket in use was swept")
+	}
+}
@@ -0,0 +1,208 @@
+package reporting
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+)
+
+// ChapterProgressJoins returns the LEFT JOINs that attach a user's video and
+// quiz progress rows (aliased vp and qp) to chapters aliased c. userExpr is
+// the SQL expression for the user ID, either a placeholder or a column.
+func ChapterProgressJoins(userExpr string) string {
+	return `
+		LEFT JOIN user_progress vp ON c.id = vp.chapter_id AND vp.user_id = ` + userExpr + ` AND vp.content_type = 'video'
+		LEFT JOIN user_progress qp ON c.id = qp.chapter_id AND qp.user_id = ` + userExpr + ` AND qp.content_type = 'quiz'`
+}
+
+type cohortAccumulator struct {
+	cohort   string
+	learners map[string]bool
+	chapters []*chapterAccumulator
+	byID     map[int]*chapterAccumulator
+}
+
+type chapterAccumulator struct {
+	stats           models.CohortChapterStats
+	scoreTotal      float64
+	scored          int
+	completionHours float64
+	completed       int
+}
+
+func CohortFunnels(ctx context.Context) ([]models.CohortReport, error) {
+	correctOptions, err := correctOptionsByChapter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Progress can be saved without ever logging in, so learners come from
+	// user_progress first. Their cohort is the signup week if there is a
+	// users row, else the week of their first activity.
+	rows, err := database.DB.QueryContext(ctx, `
+		WITH learners AS (
+			SELECT p.user_id AS id, COALESCE(u.created_at, MIN(COALESCE(p.started_at, p.updated_at))) AS created_at
+			FROM user_progress p
+			LEFT JOIN users u ON u.id = p.user_id
+			GROUP BY p.user_id
+			UNION ALL
+			SELECT id, created_at FROM users
+			WHERE id NOT IN (SELECT user_id FROM user_progress)
+		)
+		SELECT
+			date(u.created_at, 'weekday 0', '-6 days') AS cohort, u.id,
+			c.id, c.title,
+			vp.id IS NOT NULL, COALESCE(vp.completed, 0),
+			qp.id IS NOT NULL, COALESCE(qp.completed, 0), COALESCE(qp.quiz_answers, '[]'),
+			COALESCE((julianday(qp.updated_at) - julianday(COALESCE(vp.started_at, qp.started_at, u.created_at))) * 24, 0)
+		FROM learners u
+		CROSS JOIN chapters c`+ChapterProgressJoins("u.id")+`
+		ORDER BY cohort, c.order_index
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cohorts []*cohortAccumulator
+	byCohort := map[string]*cohortAccumulator{}
+
+	for rows.Next() {
+		var cohort, userID, chapterTitle, answersJSON string
+		var chapterID int
+		var videoStarted, videoDone, quizStarted, quizDone bool
+		var completionHours float64
+
+		err := rows.Scan(
+			&cohort, &userID, &chapterID, &chapterTitle,
+			&videoStarted, &videoDone, &quizStarted, &quizDone, &answersJSON, &completionHours,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		acc, ok := byCohort[cohort]
+		if !ok {
+			acc = &cohortAccumulator{
+				cohort:   cohort,
+				learners: map[string]bool{},
+				byID:     map[int]*chapterAccumulator{},
+			}
+			byCohort[cohort] = acc
+			cohorts = append(cohorts, acc)
+		}
+		acc.learners[userID] = true
+
+		ch, ok := acc.byID[chapterID]
+		if !ok {
+			ch = &chapterAccumulator{stats: models.CohortChapterStats{ChapterID: chapterID, ChapterTitle: chapterTitle}}
+			acc.byID[chapterID] = ch
+			acc.chapters = append(acc.chapters, ch)
+		}
+
+		switch {
+		case quizDone:
+			ch.stats.QuizDone++
+			var answers []int
+			if err := json.Unmarshal([]byte(answersJSON), &answers); err == nil {
+				ch.scoreTotal += quizScore(answers, correctOptions[chapterID])
+				ch.scored++
+			}
+			ch.completionHours += completionHours
+			ch.completed++
+		case videoDone:
+			ch.stats.VideoDone++
+		case videoStarted || quizStarted:
+			ch.stats.InProgress++
+		default:
+			ch.stats.NotStarted++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reports := make([]models.CohortReport, 0, len(cohorts))
+	for _, acc := range cohorts {
+		report := models.CohortReport{Cohort: acc.cohort, Learners: len(acc.learners)}
+		for _, ch := range acc.chapters {
+			if ch.scored > 0 {
+				ch.stats.AverageScore = ch.scoreTotal / float64(ch.scored)
+			}
+			if ch.completed > 0 {
+				ch.stats.AverageCompletionHours = ch.completionHours / float64(ch.completed)
+			}
+			report.Chapters = append(report.Chapters, ch.stats)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func WriteCohortCSV(w io.Writer, reports []models.CohortReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"cohort", "learners", "chapter_id", "chapter_title",
+		"not_started", "in_progress", "video_done", "quiz_done",
+		"average_score", "average_completion_hours",
+	})
+
+	for _, report := range reports {
+		for _, ch := range report.Chapters {
+			cw.Write([]string{
+				report.Cohort,
+				strconv.Itoa(report.Learners),
+				strconv.Itoa(ch.ChapterID),
+				ch.ChapterTitle,
+				strconv.Itoa(ch.NotStarted),
+				strconv.Itoa(ch.InProgress),
+				strconv.Itoa(ch.VideoDone),
+				strconv.Itoa(ch.QuizDone),
+				strconv.FormatFloat(ch.AverageScore, 'f', 2, 64),
+				strconv.FormatFloat(ch.AverageCompletionHours, 'f', 2, 64),
+			})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func correctOptionsByChapter(ctx context.Context) (map[int][]int, error) {
+	rows, err := database.DB.QueryContext(ctx, "SELECT chapter_id, correct_option FROM quiz_questions ORDER BY chapter_id, order_index")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := map[int][]int{}
+	for rows.Next() {
+		var chapterID, correct int
+		if err := rows.Scan(&chapterID, &correct); err != nil {
+			return nil, err
+		}
+		options[chapterID] = append(options[chapterID], correct)
+	}
+
+	return options, rows.Err()
+}
+
+func quizScore(answers, correctOptions []int) float64 {
+	if len(correctOptions) == 0 {
+		return 0
+	}
+
+	correct := 0
+	for i, opt := range correctOptions {
+		if i < len(answers) && answers[i] == opt {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(correctOptions)) * 100
+}
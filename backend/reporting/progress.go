@@ -0,0 +1,195 @@
+package reporting
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+)
+
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+type ProgressFilter struct {
+	From      time.Time
+	To        time.Time
+	ChapterID int
+	UserID    string
+}
+
+type ProgressEncoder interface {
+	Encode(row models.ProgressExportRow) error
+	Close() error
+}
+
+// StreamProgress runs the export query and hands each user/chapter row to fn
+// as soon as it is scanned, so callers never hold the full result in memory.
+func StreamProgress(ctx context.Context, filter ProgressFilter, fn func(models.ProgressExportRow) error) error {
+	correctOptions, err := correctOptionsByChapter(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT
+			p.user_id, c.id, c.title,
+			COALESCE(vp.video_timestamp, 0), COALESCE(vp.video_duration, 0), COALESCE(vp.completed, 0),
+			strftime('%Y-%m-%dT%H:%M:%SZ', vp.updated_at),
+			COALESCE(qp.quiz_question_index, 0), COALESCE(qp.quiz_answers, '[]'), COALESCE(qp.completed, 0),
+			strftime('%Y-%m-%dT%H:%M:%SZ', qp.updated_at)
+		FROM (SELECT DISTINCT user_id, chapter_id FROM user_progress) p
+		JOIN chapters c ON c.id = p.chapter_id` + ChapterProgressJoins("p.user_id") + `
+		WHERE 1 = 1`
+
+	lastActivity := "MAX(COALESCE(vp.updated_at, ''), COALESCE(qp.updated_at, ''))"
+	var args []interface{}
+	if !filter.From.IsZero() {
+		query += " AND " + lastActivity + " >= ?"
+		args = append(args, filter.From.UTC().Format(sqliteTimeFormat))
+	}
+	if !filter.To.IsZero() {
+		query += " AND " + lastActivity + " < ?"
+		args = append(args, filter.To.UTC().Format(sqliteTimeFormat))
+	}
+	if filter.ChapterID != 0 {
+		query += " AND c.id = ?"
+		args = append(args, filter.ChapterID)
+	}
+	if filter.UserID != "" {
+		query += " AND p.user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	query += " ORDER BY p.user_id, c.order_index"
+
+	rows, err := database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.ProgressExportRow
+		var videoUpdatedAt, quizUpdatedAt sql.NullString
+		var answersJSON string
+
+		err := rows.Scan(
+			&row.UserID, &row.ChapterID, &row.ChapterTitle,
+			&row.VideoTimestamp, &row.VideoDuration, &row.VideoCompleted, &videoUpdatedAt,
+			&row.QuizQuestionIndex, &answersJSON, &row.QuizCompleted, &quizUpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		row.VideoUpdatedAt = videoUpdatedAt.String
+		row.QuizUpdatedAt = quizUpdatedAt.String
+
+		var answers []int
+		if err := json.Unmarshal([]byte(answersJSON), &answers); err == nil {
+			row.QuizScore = quizScore(answers, correctOptions[row.ChapterID])
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func NewProgressEncoder(format string, w io.Writer) ProgressEncoder {
+	switch format {
+	case "csv":
+		return newProgressCSVEncoder(w)
+	case "ndjson":
+		return &progressNDJSONEncoder{enc: json.NewEncoder(w)}
+	default:
+		return &progressJSONEncoder{w: w}
+	}
+}
+
+type progressCSVEncoder struct {
+	cw *csv.Writer
+}
+
+func newProgressCSVEncoder(w io.Writer) *progressCSVEncoder {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"user_id", "chapter_id", "chapter_title",
+		"video_timestamp", "video_duration", "video_completed", "video_updated_at",
+		"quiz_question_index", "quiz_completed", "quiz_score", "quiz_updated_at",
+	})
+	return &progressCSVEncoder{cw: cw}
+}
+
+func (e *progressCSVEncoder) Encode(row models.ProgressExportRow) error {
+	e.cw.Write([]string{
+		row.UserID,
+		strconv.Itoa(row.ChapterID),
+		row.ChapterTitle,
+		strconv.FormatFloat(row.VideoTimestamp, 'f', 2, 64),
+		strconv.FormatFloat(row.VideoDuration, 'f', 2, 64),
+		strconv.FormatBool(row.VideoCompleted),
+		row.VideoUpdatedAt,
+		strconv.Itoa(row.QuizQuestionIndex),
+		strconv.FormatBool(row.QuizCompleted),
+		strconv.FormatFloat(row.QuizScore, 'f', 2, 64),
+		row.QuizUpdatedAt,
+	})
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+func (e *progressCSVEncoder) Close() error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+type progressNDJSONEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *progressNDJSONEncoder) Encode(row models.ProgressExportRow) error {
+	return e.enc.Encode(row)
+}
+
+func (e *progressNDJSONEncoder) Close() error {
+	return nil
+}
+
+type progressJSONEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *progressJSONEncoder) Encode(row models.ProgressExportRow) error {
+	prefix := ","
+	if e.count == 0 {
+		prefix = "["
+	}
+	e.count++
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, prefix); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *progressJSONEncoder) Close() error {
+	closing := "]\n"
+	if e.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(e.w, closing)
+	return err
+}
@@ -0,0 +1,66 @@
+package reporting
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"resume-learning-backend/database"
+	"resume-learning-backend/models"
+)
+
+func openSeededDB(t *testing.T) {
+	t.Helper()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := database.InitDB("sqlite", filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.CloseDB() })
+	if err := database.SeedData(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStreamProgressAllowsConcurrentWrites checks that learners can keep
+// saving progress while an export holds its read cursor open.
+func TestStreamProgressAllowsConcurrentWrites(t *testing.T) {
+	openSeededDB(t)
+	ctx := context.Background()
+
+	for _, userID := range []string{"u1", "u2", "u3"} {
+		if _, err := database.DB.ExecContext(ctx, `
+			INSERT INTO user_progress (user_id, chapter_id, content_type, video_timestamp)
+			VALUES (?, 1, 'video', 10)
+		`, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	streamed := 0
+	err := StreamProgress(ctx, ProgressFilter{}, func(row models.ProgressExportRow) error {
+		streamed++
+		_, err := database.DB.ExecContext(ctx, `
+			UPDATE user_progress SET video_timestamp = video_timestamp + 1
+			WHERE user_id = ? AND chapter_id = ? AND content_type = 'video'
+		`, row.UserID, row.ChapterID)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("write during export failed: %v", err)
+	}
+	if streamed != 3 {
+		t.Errorf("streamed %d rows, want 3", streamed)
+	}
+
+	var timestamp float64
+	if err := database.DB.QueryRowContext(ctx,
+		"SELECT video_timestamp FROM user_progress WHERE user_id = 'u1' AND content_type = 'video'",
+	).Scan(&timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if timestamp != 11 {
+		t.Errorf("video_timestamp = %v after concurrent update, want 11", timestamp)
+	}
+}
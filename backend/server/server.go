@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"resume-learning-backend/config"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+	shutdownTimeout   = 20 * time.Second
+)
+
+type Server struct {
+	httpServer      *http.Server
+	certFile        string
+	keyFile         string
+	shutdownTimeout time.Duration
+	hooks           []func(context.Context) error
+}
+
+func New(cfg *config.Config, handler http.Handler) *Server {
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              cfg.ListenAddr,
+			Handler:           handler,
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       readTimeout,
+			WriteTimeout:      writeTimeout,
+			IdleTimeout:       idleTimeout,
+		},
+		certFile:        cfg.TLSCertFile,
+		keyFile:         cfg.TLSKeyFile,
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+// OnShutdown registers a cleanup step to run once the HTTP server has
+// drained. Hooks run in reverse registration order, so resources opened
+// first are released last.
+func (s *Server) OnShutdown(fn func(context.Context) error) {
+	s.hooks = append(s.hooks, fn)
+}
+
+// OnDrain registers fn to run as soon as shutdown begins, before in-flight
+// requests are waited on. It is for ending long-lived responses such as
+// event streams, which would otherwise hold the drain open until timeout.
+func (s *Server) OnDrain(fn func()) {
+	s.httpServer.RegisterOnShutdown(fn)
+}
+
+// Run serves until ctx is cancelled, then stops accepting connections, waits
+// for in-flight requests to finish within the shutdown deadline and runs the
+// registered shutdown hooks.
+func (s *Server) Run(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if s.certFile != "" {
+			slog.Info("Server starting", "addr", s.httpServer.Addr, "tls", true)
+			errCh <- s.httpServer.ListenAndServeTLS(s.certFile, s.keyFile)
+		} else {
+			slog.Info("Server starting", "addr", s.httpServer.Addr, "tls", false)
+			errCh <- s.httpServer.ListenAndServe()
+		}
+	}()
+
+	var serveErr error
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			serveErr = err
+		}
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining connections")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	errs := []error{serveErr}
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	for i := len(s.hooks) - 1; i >= 0; i-- {
+		if err := s.hooks[i](shutdownCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}
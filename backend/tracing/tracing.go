@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"resume-learning-backend/buildinfo"
+	"resume-learning-backend/config"
+	"resume-learning-backend/metrics"
+)
+
+const serviceName = "resume-learning-backend"
+
+var tracer = otel.Tracer(serviceName)
+
+// Setup installs the global tracer provider for the configured exporter and
+// returns a function that flushes and stops it. With the "none" exporter
+// the default no-op provider stays in place.
+func Setup(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch cfg.TracingExporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx, otlpOptions(cfg.OTLPEndpoint)...)
+	default:
+		return func(context.Context) error { return nil }, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(buildinfo.Get().Commit),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+func otlpOptions(endpoint string) []otlptracehttp.Option {
+	switch {
+	case strings.HasPrefix(endpoint, "http://"):
+		return []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(strings.TrimPrefix(endpoint, "http://")),
+			otlptracehttp.WithInsecure(),
+		}
+	case strings.HasPrefix(endpoint, "https://"):
+		return []otlptracehttp.Option{otlptracehttp.WithEndpoint(strings.TrimPrefix(endpoint, "https://"))}
+	default:
+		return []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure()}
+	}
+}
+
+// Middleware starts a server span per request, named after the matched
+// route template.
+func Middleware() mux.MiddlewareFunc {
+	return otelmux.Middleware(serviceName)
+}
+
+func QueryHook(ctx context.Context, query string) (context.Context, func(error)) {
+	ctx, span := tracer.Start(ctx, "db "+metrics.QueryName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemSqlite,
+			attribute.String("db.statement", strings.Join(strings.Fields(query), " ")),
+		),
+	)
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
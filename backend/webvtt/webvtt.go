@@ -0,0 +1,158 @@
+package webvtt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Cue is one timed caption. Text is plain text: markup such as <v Speaker>
+// or <i> is removed and character references are decoded.
+type Cue struct {
+	Start float64
+	End   float64
+	Text  string
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Parse reads a WebVTT file and returns its cues in file order. NOTE, STYLE
+// and REGION blocks are skipped. Errors name the offending line.
+func Parse(data []byte) ([]Cue, error) {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	// WebVTT lines may end in CRLF, LF or a lone CR; bufio.Scanner only
+	// splits on LF, so everything is normalized to that first.
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	var blocks [][]string
+	var lineNumbers []int
+	var block []string
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		if len(block) == 0 {
+			lineNumbers = append(lineNumbers, line)
+		}
+		block = append(block, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+
+	if len(blocks) == 0 || !isSignature(blocks[0][0]) {
+		return nil, fmt.Errorf("line 1: file must start with WEBVTT")
+	}
+
+	var cues []Cue
+	for i, b := range blocks[1:] {
+		first := lineNumbers[i+1]
+		switch {
+		case strings.HasPrefix(b[0], "NOTE"), b[0] == "STYLE", b[0] == "REGION":
+			continue
+		}
+
+		timing := 0
+		if !strings.Contains(b[0], "-->") {
+			timing = 1 // the first line is a cue identifier
+		}
+		if timing >= len(b) || !strings.Contains(b[timing], "-->") {
+			return nil, fmt.Errorf("line %d: expected a cue timing line", first)
+		}
+
+		start, end, err := parseTiming(b[timing])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", first+timing, err)
+		}
+
+		cues = append(cues, Cue{
+			Start: start,
+			End:   end,
+			Text:  plainText(b[timing+1:]),
+		})
+	}
+
+	return cues, nil
+}
+
+func isSignature(line string) bool {
+	return line == "WEBVTT" || strings.HasPrefix(line, "WEBVTT ") || strings.HasPrefix(line, "WEBVTT\t")
+}
+
+// parseTiming parses "00:01.000 --> 00:04.000 [settings]".
+func parseTiming(line string) (float64, float64, error) {
+	from, rest, _ := strings.Cut(line, "-->")
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("missing cue end time")
+	}
+
+	start, err := parseTimestamp(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseTimestamp(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("cue ends before it starts")
+	}
+
+	return start, end, nil
+}
+
+// parseTimestamp parses "hh:mm:ss.ttt" or "mm:ss.ttt" into seconds.
+func parseTimestamp(s string) (float64, error) {
+	invalid := fmt.Errorf("invalid timestamp %q", s)
+
+	clock, millis, ok := strings.Cut(s, ".")
+	if !ok || len(millis) != 3 {
+		return 0, invalid
+	}
+	parts := strings.Split(clock, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, invalid
+	}
+
+	var total float64
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (i > 0 && (len(p) != 2 || n > 59)) {
+			return 0, invalid
+		}
+		total = total*60 + float64(n)
+	}
+
+	ms, err := strconv.Atoi(millis)
+	if err != nil {
+		return 0, invalid
+	}
+
+	return total + float64(ms)/1000, nil
+}
+
+func plainText(lines []string) string {
+	text := strings.Join(lines, " ")
+	text = tagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,121 @@
+package webvtt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Cue
+		wantErr string
+	}{
+		{
+			name:  "minimal",
+			input: "WEBVTT\n\n00:01.000 --> 00:04.000\nHello\n",
+			want:  []Cue{{Start: 1, End: 4, Text: "Hello"}},
+		},
+		{
+			name:  "header text and byte order mark",
+			input: "\uFEFFWEBVTT - Chapter 1\nKind: captions\n\n00:01.000 --> 00:02.500\nHi\n",
+			want:  []Cue{{Start: 1, End: 2.5, Text: "Hi"}},
+		},
+		{
+			name:  "hours, identifier and cue settings",
+			input: "WEBVTT\n\nintro\n01:02:03.250 --> 01:02:05.000 align:start position:10%\nWelcome\n",
+			want:  []Cue{{Start: 3723.25, End: 3725, Text: "Welcome"}},
+		},
+		{
+			name:  "multi-line payload with markup",
+			input: "WEBVTT\n\n00:01.000 --> 00:04.000\n<v Ana>Hello</v>\n<i>and welcome</i> &amp; enjoy\n",
+			want:  []Cue{{Start: 1, End: 4, Text: "Hello and welcome & enjoy"}},
+		},
+		{
+			name: "NOTE, STYLE and REGION blocks",
+			input: "WEBVTT\n\nNOTE written by hand\nspans two lines\n\nSTYLE\n::cue { color: yellow }\n\n" +
+				"REGION\nid:bottom\n\n00:01.000 --> 00:02.000\nOne\n\nNOTE between cues\n\n00:02.000 --> 00:03.000\nTwo\n",
+			want: []Cue{{Start: 1, End: 2, Text: "One"}, {Start: 2, End: 3, Text: "Two"}},
+		},
+		{
+			name:  "CRLF line endings",
+			input: "WEBVTT\r\n\r\n00:01.000 --> 00:02.000\r\nOne\r\n\r\n00:02.000 --> 00:03.000\r\nTwo\r\n",
+			want:  []Cue{{Start: 1, End: 2, Text: "One"}, {Start: 2, End: 3, Text: "Two"}},
+		},
+		{
+			name:  "CR-only line endings",
+			input: "WEBVTT\r\r00:01.000 --> 00:02.000\rOne\r\r00:02.000 --> 00:03.000\rTwo\r",
+			want:  []Cue{{Start: 1, End: 2, Text: "One"}, {Start: 2, End: 3, Text: "Two"}},
+		},
+		{
+			name:  "zero-length cue",
+			input: "WEBVTT\n\n00:01.000 --> 00:01.000\nBeep\n",
+			want:  []Cue{{Start: 1, End: 1, Text: "Beep"}},
+		},
+		{
+			name:    "missing header",
+			input:   "00:01.000 --> 00:04.000\nHello\n",
+			wantErr: "line 1: file must start with WEBVTT",
+		},
+		{
+			name:    "header without separator",
+			input:   "WEBVTTX\n\n00:01.000 --> 00:04.000\nHello\n",
+			wantErr: "line 1: file must start with WEBVTT",
+		},
+		{
+			name:    "empty file",
+			input:   "",
+			wantErr: "line 1: file must start with WEBVTT",
+		},
+		{
+			name:    "block without timing",
+			input:   "WEBVTT\n\nintro\nHello\n",
+			wantErr: "line 3: expected a cue timing line",
+		},
+		{
+			name:    "bad start timestamp",
+			input:   "WEBVTT\n\n00:01 --> 00:04.000\nHello\n",
+			wantErr: `line 3: invalid timestamp "00:01"`,
+		},
+		{
+			name:    "minutes out of range",
+			input:   "WEBVTT\n\n00:60.000 --> 01:04.000\nHello\n",
+			wantErr: `line 3: invalid timestamp "00:60.000"`,
+		},
+		{
+			name:    "bad end timestamp after identifier",
+			input:   "WEBVTT\n\nintro\n00:01.000 --> 0:04.0\nHello\n",
+			wantErr: `line 4: invalid timestamp "0:04.0"`,
+		},
+		{
+			name:    "missing end time",
+			input:   "WEBVTT\n\n00:01.000 -->\nHello\n",
+			wantErr: "line 3: missing cue end time",
+		},
+		{
+			name:    "end before start",
+			input:   "WEBVTT\n\n00:05.000 --> 00:04.000\nHello\n",
+			wantErr: "line 3: cue ends before it starts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
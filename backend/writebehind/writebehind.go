@@ -0,0 +1,178 @@
+package writebehind
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"resume-learning-backend/models"
+)
+
+// VideoWrite is one accepted video position save waiting to be written.
+type VideoWrite struct {
+	UserID     string
+	DeviceID   string
+	DeviceName string
+	Request    models.VideoProgressRequest
+	// MaxTimestamp is the furthest position among the saves coalesced into
+	// this one, which may be past Request.Timestamp after a seek back.
+	MaxTimestamp float64
+	At           time.Time
+}
+
+// closeFlushTimeout bounds the final flush in Close. It gets its own
+// deadline because Close runs after the HTTP server has drained, by which
+// time the shutdown context may already have expired.
+const closeFlushTimeout = 10 * time.Second
+
+// FlushFunc persists a batch of writes, ordered by At.
+type FlushFunc func(ctx context.Context, writes []VideoWrite) error
+
+type key struct {
+	userID    string
+	chapterID int
+	deviceID  string
+}
+
+// Buffer coalesces video heartbeats in memory, keeping only the latest
+// position per user, chapter and device, and writes them in batches on an
+// interval. Readers that need the freshest state call FlushUser first.
+type Buffer struct {
+	interval time.Duration
+	flush    FlushFunc
+
+	mu      sync.Mutex
+	pending map[key]VideoWrite
+
+	// flushMu serializes flushes so batches are written in the order their
+	// entries were taken from pending.
+	flushMu sync.Mutex
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(interval time.Duration, flush FlushFunc) *Buffer {
+	return &Buffer{
+		interval: interval,
+		flush:    flush,
+		pending:  make(map[key]VideoWrite),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start flushes the buffer every interval until Close is called.
+func (b *Buffer) Start() {
+	b.started.Store(true)
+	go func() {
+		defer close(b.done)
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				if err := b.Flush(context.Background()); err != nil {
+					slog.Error("Failed to flush video progress", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Put buffers a write, replacing any pending write for the same user,
+// chapter and device. It reports false, and keeps the pending write, when
+// the new one carries a lower client_sequence.
+func (b *Buffer) Put(w VideoWrite) bool {
+	k := key{w.UserID, w.Request.ChapterID, w.DeviceID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w.MaxTimestamp = math.Max(w.MaxTimestamp, w.Request.Timestamp)
+	if prev, ok := b.pending[k]; ok {
+		if w.Request.ClientSequence != 0 && w.Request.ClientSequence < prev.Request.ClientSequence {
+			return false
+		}
+		w.MaxTimestamp = math.Max(w.MaxTimestamp, prev.MaxTimestamp)
+		w.Request.Duration = math.Max(w.Request.Duration, prev.Request.Duration)
+	}
+	b.pending[k] = w
+
+	return true
+}
+
+// Flush writes every pending write.
+func (b *Buffer) Flush(ctx context.Context) error {
+	return b.flushMatching(ctx, func(key) bool { return true })
+}
+
+// FlushUser writes the user's pending writes, so that reads which follow
+// see them.
+func (b *Buffer) FlushUser(ctx context.Context, userID string) error {
+	return b.flushMatching(ctx, func(k key) bool { return k.userID == userID })
+}
+
+func (b *Buffer) flushMatching(ctx context.Context, match func(key) bool) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	var writes []VideoWrite
+	for k, w := range b.pending {
+		if match(k) {
+			writes = append(writes, w)
+			delete(b.pending, k)
+		}
+	}
+	b.mu.Unlock()
+
+	if len(writes) == 0 {
+		return nil
+	}
+
+	sort.Slice(writes, func(i, j int) bool { return writes[i].At.Before(writes[j].At) })
+
+	err := b.flush(ctx, writes)
+	if err != nil {
+		// Put failed writes back so the next flush retries them. A newer
+		// write that arrived meanwhile wins but keeps the furthest position.
+		b.mu.Lock()
+		for _, w := range writes {
+			k := key{w.UserID, w.Request.ChapterID, w.DeviceID}
+			if newer, ok := b.pending[k]; ok {
+				newer.MaxTimestamp = math.Max(newer.MaxTimestamp, w.MaxTimestamp)
+				b.pending[k] = newer
+			} else {
+				b.pending[k] = w
+			}
+		}
+		b.mu.Unlock()
+	}
+
+	return err
+}
+
+// Close stops the interval flush, if Start was called, and writes whatever
+// is still pending. The final flush keeps ctx's values but not its deadline
+// or cancellation, and is limited to closeFlushTimeout instead.
+func (b *Buffer) Close(ctx context.Context) error {
+	b.stopOnce.Do(func() { close(b.stop) })
+	if b.started.Load() {
+		<-b.done
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeFlushTimeout)
+	defer cancel()
+	return b.Flush(ctx)
+}
@@ -0,0 +1,81 @@
+package writebehind
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"resume-learning-backend/models"
+)
+
+func videoWrite(userID string, timestamp float64) VideoWrite {
+	return VideoWrite{
+		UserID:  userID,
+		Request: models.VideoProgressRequest{ChapterID: 1, Timestamp: timestamp},
+		At:      time.Now(),
+	}
+}
+
+func TestCloseWithoutStart(t *testing.T) {
+	var flushed []VideoWrite
+	b := New(time.Hour, func(ctx context.Context, writes []VideoWrite) error {
+		flushed = append(flushed, writes...)
+		return nil
+	})
+	b.Put(videoWrite("u1", 30))
+
+	done := make(chan error, 1)
+	go func() { done <- b.Close(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Close returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked on a buffer that was never started")
+	}
+	if len(flushed) != 1 {
+		t.Errorf("flushed %d writes on Close, want 1", len(flushed))
+	}
+}
+
+func TestCloseFlushesAfterShutdownDeadline(t *testing.T) {
+	var flushed []VideoWrite
+	b := New(time.Hour, func(ctx context.Context, writes []VideoWrite) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		flushed = append(flushed, writes...)
+		return nil
+	})
+	b.Start()
+	b.Put(videoWrite("u1", 30))
+	b.Put(videoWrite("u2", 45))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := b.Close(ctx); err != nil {
+		t.Fatalf("Close with an expired context returned %v", err)
+	}
+	if len(flushed) != 2 {
+		t.Errorf("flushed %d writes on Close, want 2", len(flushed))
+	}
+}
+
+func TestPutKeepsNewerSequence(t *testing.T) {
+	b := New(time.Hour, func(context.Context, []VideoWrite) error { return nil })
+
+	newer := videoWrite("u1", 60)
+	newer.Request.ClientSequence = 5
+	if !b.Put(newer) {
+		t.Fatal("first write was rejected")
+	}
+
+	older := videoWrite("u1", 10)
+	older.Request.ClientSequence = 4
+	if b.Put(older) {
+		t.Error("write with a lower client_sequence was accepted")
+	}
+}
@@ -44,8 +44,9 @@ class ApiService {
     final response = await http.get(Uri.parse('$baseUrl/chapters'), headers: _headers);
 
     if (response.statusCode == 200) {
-      final List<dynamic> data = jsonDecode(response.body);
-      return data.map((json) => Chapter.fromJson(json)).toList();
+      final Map<String, dynamic> data = jsonDecode(response.body);
+      final List<dynamic> chapters = data['chapters'];
+      return chapters.map((json) => Chapter.fromJson(json)).toList();
     } else {
       throw Exception('Failed to load chapters: ${response.body}');
     }